@@ -0,0 +1,73 @@
+package weather
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// adaptiveWindow is the number of recent latencies that adaptive timeouts
+	// average over.
+	adaptiveWindow = 10
+
+	// adaptiveFactor is the multiple of the average latency that requests are
+	// given, so that ordinary variation doesn't cause timeouts.
+	adaptiveFactor = 3
+)
+
+// WithAdaptiveTimeout sets the timeout of each request to three times the
+// average latency of the last ten requests, clamped to between min and max.
+// Until a request has completed, the timeout is max. The adaptive timeout
+// replaces the timeouts set by WithForecastTimeout and the default timeout.
+func WithAdaptiveTimeout(min, max time.Duration) Option {
+	return func(c *Client) {
+		c.latencies = &latencyTracker{min: min, max: max}
+	}
+}
+
+type latencyTracker struct {
+	min, max time.Duration
+
+	mu      sync.Mutex
+	samples [adaptiveWindow]time.Duration
+	count   int
+	next    int
+}
+
+// observe records the latency of a request. It is a no-op on a nil
+// *latencyTracker so that callers don't need to check whether adaptive
+// timeouts are enabled.
+func (t *latencyTracker) observe(latency time.Duration) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.samples[t.next] = latency
+	t.next = (t.next + 1) % adaptiveWindow
+	if t.count < adaptiveWindow {
+		t.count++
+	}
+}
+
+// timeout returns the timeout for the next request.
+func (t *latencyTracker) timeout() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.count == 0 {
+		return t.max
+	}
+	var total time.Duration
+	for _, s := range t.samples[:t.count] {
+		total += s
+	}
+	d := adaptiveFactor * total / time.Duration(t.count)
+	switch {
+	case d < t.min:
+		return t.min
+	case d > t.max:
+		return t.max
+	default:
+		return d
+	}
+}
@@ -0,0 +1,63 @@
+package weather
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLatencyTrackerTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		samples []time.Duration
+		want    time.Duration
+	}{
+		{"no samples", nil, 10 * time.Second},
+		{"within bounds", []time.Duration{time.Second, 2 * time.Second}, 4500 * time.Millisecond},
+		{"clamped to min", []time.Duration{time.Millisecond}, time.Second},
+		{"clamped to max", []time.Duration{time.Minute}, 10 * time.Second},
+		{"last ten only", append(repeatDuration(time.Minute, 5), repeatDuration(time.Second, adaptiveWindow)...), 3 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := &latencyTracker{min: time.Second, max: 10 * time.Second}
+			for _, s := range tt.samples {
+				tracker.observe(s)
+			}
+			if got := tracker.timeout(); got != tt.want {
+				t.Errorf("timeout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func repeatDuration(d time.Duration, n int) []time.Duration {
+	ds := make([]time.Duration, n)
+	for i := range ds {
+		ds[i] = d
+	}
+	return ds
+}
+
+func TestWithAdaptiveTimeout(t *testing.T) {
+	c := NewClient(WithAdaptiveTimeout(time.Second, 10*time.Second), WithForecastTimeout(time.Minute))
+	if got := c.requestTimeout("data/2.5/forecast"); got != 10*time.Second {
+		t.Errorf("requestTimeout() before any requests = %v, want the max", got)
+	}
+	c.latencies.observe(2 * time.Second)
+	if got := c.requestTimeout("data/2.5/weather"); got != 6*time.Second {
+		t.Errorf("requestTimeout() = %v, want 6s", got)
+	}
+
+	var nilTracker *latencyTracker
+	nilTracker.observe(time.Second)
+
+	c, done := newTestClient(respond(`{"main":{"temp":1}}`), WithAdaptiveTimeout(time.Second, 10*time.Second))
+	defer done()
+	if _, err := c.GetCurrentWeather(context.Background(), "12345"); err != nil {
+		t.Fatalf("GetCurrentWeather() error = %v", err)
+	}
+	if c.latencies.count != 1 {
+		t.Errorf("recorded %d latencies, want 1", c.latencies.count)
+	}
+}
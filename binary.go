@@ -0,0 +1,116 @@
+package weather
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+const binaryFormatVersion = 1
+
+var binaryUnits = []Units{"", Kelvin, Imperial, Metric}
+
+// SerializeCompact encodes the forecast in a compact binary format. Only Date,
+// Temperature, TemperatureMin, TemperatureMax, FeelsLike, Humidity and Units
+// are kept. Dates are stored to the second as varint deltas, and the other
+// values as int16s in tenths, so they lose precision beyond 0.1. The method
+// is deliberately not named MarshalBinary, so that encoders such as
+// encoding/gob keep encoding every field.
+func (f Forecast) SerializeCompact() ([]byte, error) {
+	buf := make([]byte, 0, 1+binary.MaxVarintLen64+len(f)*(binary.MaxVarintLen64+11))
+	buf = append(buf, binaryFormatVersion)
+	buf = appendUvarint(buf, uint64(len(f)))
+
+	var prev int64
+	for i, w := range f {
+		ts := w.Date.Unix()
+		buf = appendVarint(buf, ts-prev)
+		prev = ts
+
+		unit := -1
+		for j, u := range binaryUnits {
+			if u == w.Units {
+				unit = j
+			}
+		}
+		if unit < 0 {
+			return nil, fmt.Errorf("weather: entry %d: unknown units %q", i, w.Units)
+		}
+		buf = append(buf, byte(unit))
+
+		for _, v := range []float64{w.Temperature, w.TemperatureMin, w.TemperatureMax, w.FeelsLike, w.Humidity} {
+			scaled := math.Round(v * 10)
+			if scaled < math.MinInt16 || scaled > math.MaxInt16 {
+				return nil, fmt.Errorf("weather: entry %d: value %g out of range", i, v)
+			}
+			buf = append(buf, 0, 0)
+			binary.BigEndian.PutUint16(buf[len(buf)-2:], uint16(int16(scaled)))
+		}
+	}
+	return buf, nil
+}
+
+var errBinaryTruncated = errors.New("weather: truncated binary forecast")
+
+// DeserializeCompact decodes a forecast encoded by SerializeCompact into f.
+func (f *Forecast) DeserializeCompact(data []byte) error {
+	if len(data) == 0 || data[0] != binaryFormatVersion {
+		return errors.New("weather: unsupported binary forecast version")
+	}
+	data = data[1:]
+
+	count, n := binary.Uvarint(data)
+	if n <= 0 {
+		return errBinaryTruncated
+	}
+	data = data[n:]
+
+	// Every entry takes at least 12 bytes, which bounds the allocation for
+	// a corrupt count.
+	if count > uint64(len(data)/12) {
+		return errBinaryTruncated
+	}
+	forecast := make(Forecast, 0, count)
+	var ts int64
+	for i := uint64(0); i < count; i++ {
+		delta, n := binary.Varint(data)
+		if n <= 0 {
+			return errBinaryTruncated
+		}
+		data = data[n:]
+		ts += delta
+
+		if len(data) < 11 {
+			return errBinaryTruncated
+		}
+		if int(data[0]) >= len(binaryUnits) {
+			return fmt.Errorf("weather: entry %d: unknown units", i)
+		}
+		w := Weather{
+			Date:  time.Unix(ts, 0),
+			Units: binaryUnits[data[0]],
+		}
+		data = data[1:]
+
+		for _, v := range []*float64{&w.Temperature, &w.TemperatureMin, &w.TemperatureMax, &w.FeelsLike, &w.Humidity} {
+			*v = float64(int16(binary.BigEndian.Uint16(data))) / 10
+			data = data[2:]
+		}
+		forecast = append(forecast, w)
+	}
+
+	*f = forecast
+	return nil
+}
+
+func appendUvarint(buf []byte, v uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	return append(buf, tmp[:binary.PutUvarint(tmp[:], v)]...)
+}
+
+func appendVarint(buf []byte, v int64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	return append(buf, tmp[:binary.PutVarint(tmp[:], v)]...)
+}
@@ -0,0 +1,116 @@
+package weather
+
+import (
+	"bytes"
+	"encoding/gob"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSerializeCompactRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		forecast Forecast
+	}{
+		{"empty", Forecast{}},
+		{"single", Forecast{
+			{Date: time.Unix(1600000000, 0), Temperature: 21.34, TemperatureMin: 19.96, TemperatureMax: 23.05, FeelsLike: 20.5, Humidity: 64, Units: Metric},
+		}},
+		{"negative and mixed units", Forecast{
+			{Date: time.Unix(1600000000, 0), Temperature: -12.31, TemperatureMin: -15, TemperatureMax: -10.04, FeelsLike: -18.77, Humidity: 91, Units: Imperial},
+			{Date: time.Unix(1600010800, 0), Temperature: 280.15, TemperatureMin: 279.5, TemperatureMax: 281, FeelsLike: 277.2, Humidity: 40, Units: Kelvin},
+			{Date: time.Unix(1599990000, 0), Temperature: 3, Units: ""},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := tt.forecast.SerializeCompact()
+			if err != nil {
+				t.Fatalf("SerializeCompact: %v", err)
+			}
+			var got Forecast
+			if err := got.DeserializeCompact(b); err != nil {
+				t.Fatalf("DeserializeCompact: %v", err)
+			}
+			if len(got) != len(tt.forecast) {
+				t.Fatalf("got %d entries, want %d", len(got), len(tt.forecast))
+			}
+			for i, want := range tt.forecast {
+				g := got[i]
+				if !g.Date.Equal(want.Date) || g.Units != want.Units {
+					t.Errorf("entry %d: got date %v units %q, want %v %q", i, g.Date, g.Units, want.Date, want.Units)
+				}
+				// Values are stored in tenths, so they round to within half
+				// of that, plus floating point error.
+				const tolerance = 0.05 + 1e-9
+				for _, v := range []struct {
+					field     string
+					got, want float64
+				}{
+					{"Temperature", g.Temperature, want.Temperature},
+					{"TemperatureMin", g.TemperatureMin, want.TemperatureMin},
+					{"TemperatureMax", g.TemperatureMax, want.TemperatureMax},
+					{"FeelsLike", g.FeelsLike, want.FeelsLike},
+					{"Humidity", g.Humidity, want.Humidity},
+				} {
+					if math.Abs(v.got-v.want) > tolerance {
+						t.Errorf("entry %d: %s = %g, want %g within 0.05", i, v.field, v.got, v.want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSerializeCompactErrors(t *testing.T) {
+	if _, err := (Forecast{{Temperature: 5000}}).SerializeCompact(); err == nil {
+		t.Error("SerializeCompact with out of range value: got nil error")
+	}
+	if _, err := (Forecast{{Units: "rankine"}}).SerializeCompact(); err == nil {
+		t.Error("SerializeCompact with unknown units: got nil error")
+	}
+
+	b, err := Forecast{{Date: time.Unix(1600000000, 0), Temperature: 1}}.SerializeCompact()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, data := range [][]byte{nil, {99}, b[:len(b)-1]} {
+		var f Forecast
+		if err := f.DeserializeCompact(data); err == nil {
+			t.Errorf("DeserializeCompact(%v): got nil error", data)
+		}
+	}
+}
+
+func TestForecastGobKeepsAllFields(t *testing.T) {
+	want := Forecast{{
+		Date:                     time.Unix(1600000000, 0).UTC(),
+		Temperature:              21.34,
+		Humidity:                 64,
+		Pressure:                 1012,
+		PrecipitationProbability: 0.4,
+		ConditionID:              500,
+		Condition:                "Rain",
+		Clouds:                   75,
+		Rain:                     1.25,
+		WindSpeed:                4.1,
+		WindDirection:            220,
+		Units:                    Metric,
+		Lat:                      40.7,
+		Lon:                      -74,
+	}}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(want); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	var got Forecast
+	if err := gob.NewDecoder(&buf).Decode(&got); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("gob round trip:\n got %+v\nwant %+v", got, want)
+	}
+}
@@ -13,9 +13,8 @@ import (
 func main() {
 	ctx := context.Background()
 
-	key := os.Getenv("OPENWEATHERMAP_APIKEY")
 	c := weather.NewClient(
-		weather.WithAPIKey(key),
+		weather.WithAPIKeyFromEnv(""),
 		weather.WithUnits(weather.Imperial),
 	)
 	w, err := c.GetCurrentWeather(ctx, os.Args[1])
@@ -0,0 +1,68 @@
+package weather
+
+import (
+	"context"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWithFixtureRecorder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "weather-fixtures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c, done := newTestClient(zipHandler, WithFixtureRecorder(dir))
+	defer done()
+
+	for _, zip := range []string{"1", "2", "1", "bad"} {
+		c.GetCurrentWeather(context.Background(), zip)
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("recorded %d fixtures, want one per successful request", len(files))
+	}
+
+	params := url.Values{"zip": {"2"}}
+	body, err := ioutil.ReadFile(filepath.Join(dir, fixtureName(c.dataPath("2.5", "weather"), params)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"main":{"temp":2}}`; string(body) != want {
+		t.Errorf("fixture = %s, want %s", body, want)
+	}
+}
+
+func TestFixtureName(t *testing.T) {
+	base := url.Values{"zip": {"12345"}}
+	tests := []struct {
+		name   string
+		path   string
+		params url.Values
+		same   bool
+	}{
+		{"same request", "data/2.5/weather", url.Values{"zip": {"12345"}}, true},
+		{"different API key", "data/2.5/weather", url.Values{"zip": {"12345"}, "APPID": {"other"}}, true},
+		{"different params", "data/2.5/weather", url.Values{"zip": {"54321"}}, false},
+		{"different path", "data/2.5/forecast", url.Values{"zip": {"12345"}}, false},
+	}
+	want := fixtureName("data/2.5/weather", base)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fixtureName(tt.path, tt.params); (got == want) != tt.same {
+				t.Errorf("fixtureName() = %s, base request %s, want same %v", got, want, tt.same)
+			}
+		})
+	}
+	if _, ok := base["APPID"]; ok {
+		t.Error("fixtureName() changed its params")
+	}
+}
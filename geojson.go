@@ -0,0 +1,42 @@
+package weather
+
+import "encoding/json"
+
+type geoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+type geoJSONFeature struct {
+	Type       string                 `json:"type"`
+	Geometry   geoJSONPoint           `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type geoJSONPoint struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+// ToGeoJSON encodes the entries as a GeoJSON FeatureCollection with a point
+// feature at each entry's Lat and Lon. The properties use the same keys and
+// values as Weather.Snapshot, plus condition_group from ConditionGroup.
+func (f Forecast) ToGeoJSON() ([]byte, error) {
+	fc := geoJSONFeatureCollection{
+		Type:     "FeatureCollection",
+		Features: make([]geoJSONFeature, 0, len(f)),
+	}
+	for _, w := range f {
+		props := w.Snapshot()
+		props["condition_group"] = w.ConditionGroup()
+		fc.Features = append(fc.Features, geoJSONFeature{
+			Type: "Feature",
+			Geometry: geoJSONPoint{
+				Type:        "Point",
+				Coordinates: [2]float64{w.Lon, w.Lat},
+			},
+			Properties: props,
+		})
+	}
+	return json.Marshal(fc)
+}
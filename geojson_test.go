@@ -0,0 +1,103 @@
+package weather
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestToGeoJSON(t *testing.T) {
+	f := Forecast{
+		{
+			Date:        time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC),
+			Temperature: 21.5,
+			Units:       Metric,
+			ConditionID: 501,
+			Condition:   "Rain",
+			Lat:         40.7,
+			Lon:         -74,
+		},
+		{
+			Date:        time.Date(2020, 6, 1, 15, 0, 0, 0, time.UTC),
+			Temperature: 18,
+			Units:       Metric,
+			ConditionID: 800,
+			Condition:   "Clear",
+			Lat:         51.5,
+			Lon:         -0.1,
+		},
+	}
+	b, err := f.ToGeoJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fc struct {
+		Type     string `json:"type"`
+		Features []struct {
+			Type     string `json:"type"`
+			Geometry struct {
+				Type        string     `json:"type"`
+				Coordinates [2]float64 `json:"coordinates"`
+			} `json:"geometry"`
+			Properties map[string]interface{} `json:"properties"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal(b, &fc); err != nil {
+		t.Fatal(err)
+	}
+	if fc.Type != "FeatureCollection" || len(fc.Features) != len(f) {
+		t.Fatalf("got %s", b)
+	}
+
+	tests := []struct {
+		coordinates [2]float64
+		properties  map[string]interface{}
+	}{
+		{
+			coordinates: [2]float64{-74, 40.7},
+			properties: map[string]interface{}{
+				"date":            "2020-06-01T12:00:00Z",
+				"temperature":     21.5,
+				"units":           "metric",
+				"condition_id":    501.0,
+				"condition":       "Rain",
+				"condition_group": "Rain",
+				"lat":             40.7,
+				"lon":             -74.0,
+			},
+		},
+		{
+			coordinates: [2]float64{-0.1, 51.5},
+			properties: map[string]interface{}{
+				"date":            "2020-06-01T15:00:00Z",
+				"temperature":     18.0,
+				"units":           "metric",
+				"condition_id":    800.0,
+				"condition":       "Clear",
+				"condition_group": "Clear",
+				"lat":             51.5,
+				"lon":             -0.1,
+			},
+		},
+	}
+	for i, tt := range tests {
+		feature := fc.Features[i]
+		if feature.Type != "Feature" || feature.Geometry.Type != "Point" {
+			t.Errorf("feature %d: got type %q with geometry %q", i, feature.Type, feature.Geometry.Type)
+		}
+		if feature.Geometry.Coordinates != tt.coordinates {
+			t.Errorf("feature %d: got coordinates %v, want %v", i, feature.Geometry.Coordinates, tt.coordinates)
+		}
+		for key, want := range tt.properties {
+			if got := feature.Properties[key]; got != want {
+				t.Errorf("feature %d: property %s = %v, want %v", i, key, got, want)
+			}
+		}
+		for key := range f[i].Snapshot() {
+			if _, ok := feature.Properties[key]; !ok {
+				t.Errorf("feature %d: missing snapshot property %s", i, key)
+			}
+		}
+	}
+}
@@ -2,4 +2,7 @@ module github.com/haleyrc/weather
 
 go 1.13
 
-require github.com/davecgh/go-spew v1.1.1
+require (
+	github.com/davecgh/go-spew v1.1.1
+	golang.org/x/sync v0.0.0-20190911185100-cd5d95a43a6e
+)
@@ -0,0 +1,63 @@
+package weather
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WithMetrics enables counting of requests, errors and request latency,
+// which MetricsText reports.
+func WithMetrics() Option {
+	return func(c *Client) {
+		c.metrics = new(metrics)
+	}
+}
+
+type metrics struct {
+	mu       sync.Mutex
+	requests int64
+	errors   int64
+	latency  time.Duration
+}
+
+// observe records a request to the API. It is a no-op on a nil *metrics so
+// that callers don't need to check whether metrics are enabled.
+func (m *metrics) observe(latency time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests++
+	if err != nil {
+		m.errors++
+	}
+	m.latency += latency
+}
+
+// MetricsText returns the client's metrics in the Prometheus text exposition
+// format. It returns an empty string unless the client was created with
+// WithMetrics.
+func (c Client) MetricsText() string {
+	if c.metrics == nil {
+		return ""
+	}
+	c.metrics.mu.Lock()
+	requests, errors, latency := c.metrics.requests, c.metrics.errors, c.metrics.latency
+	c.metrics.mu.Unlock()
+
+	var b strings.Builder
+	fmt.Fprintln(&b, "# HELP weather_requests_total Total number of requests made to the API.")
+	fmt.Fprintln(&b, "# TYPE weather_requests_total counter")
+	fmt.Fprintf(&b, "weather_requests_total %d\n", requests)
+	fmt.Fprintln(&b, "# HELP weather_errors_total Total number of requests to the API that failed.")
+	fmt.Fprintln(&b, "# TYPE weather_errors_total counter")
+	fmt.Fprintf(&b, "weather_errors_total %d\n", errors)
+	fmt.Fprintln(&b, "# HELP weather_request_duration_seconds Latency of requests to the API.")
+	fmt.Fprintln(&b, "# TYPE weather_request_duration_seconds summary")
+	fmt.Fprintf(&b, "weather_request_duration_seconds_sum %g\n", latency.Seconds())
+	fmt.Fprintf(&b, "weather_request_duration_seconds_count %d\n", requests)
+	return b.String()
+}
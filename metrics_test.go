@@ -0,0 +1,50 @@
+package weather
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestMetricsText(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  []Option
+		zips  []string
+		wants []string
+	}{
+		{"disabled", nil, []string{"1"}, nil},
+		{"no requests", []Option{WithMetrics()}, nil, []string{
+			"weather_requests_total 0\n",
+			"weather_errors_total 0\n",
+			"weather_request_duration_seconds_count 0\n",
+		}},
+		{"requests and errors", []Option{WithMetrics()}, []string{"1", "bad", "2"}, []string{
+			"# TYPE weather_requests_total counter\n",
+			"weather_requests_total 3\n",
+			"weather_errors_total 1\n",
+			"# TYPE weather_request_duration_seconds summary\n",
+			"weather_request_duration_seconds_count 3\n",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, done := newTestClient(http.HandlerFunc(zipHandler), tt.opts...)
+			defer done()
+
+			for _, zip := range tt.zips {
+				c.GetCurrentWeather(context.Background(), zip)
+			}
+			text := c.MetricsText()
+			if tt.wants == nil && text != "" {
+				t.Errorf("MetricsText() = %q, want empty", text)
+			}
+			for _, want := range tt.wants {
+				if !strings.Contains(text, want) {
+					t.Errorf("MetricsText() is missing %q:\n%s", want, text)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+module github.com/haleyrc/weather/otelweather
+
+// OpenTelemetry v1.24.0 is the last release that supports Go 1.20, so the
+// adapter requires 1.20 rather than the newest OpenTelemetry's Go version.
+// The weather package itself only needs Go 1.13.
+go 1.20
+
+require (
+	github.com/haleyrc/weather v0.0.0-20261014054321-7b142591f4eb
+	go.opentelemetry.io/otel v1.24.0
+	go.opentelemetry.io/otel/sdk v1.24.0
+	go.opentelemetry.io/otel/trace v1.24.0
+)
+
+require (
+	github.com/go-logr/logr v1.4.1 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	go.opentelemetry.io/otel/metric v1.24.0 // indirect
+	golang.org/x/sync v0.0.0-20190911185100-cd5d95a43a6e // indirect
+	golang.org/x/sys v0.17.0 // indirect
+)
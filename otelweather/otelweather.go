@@ -0,0 +1,61 @@
+// Package otelweather adapts OpenTelemetry tracers for use with
+// weather.WithTracer. It is a separate module so that the weather package
+// itself doesn't depend on OpenTelemetry.
+package otelweather
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/haleyrc/weather"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracer returns a weather.Tracer that starts client spans with t, for
+// example:
+//
+//	weather.WithTracer(otelweather.Tracer(otel.Tracer("weather")))
+func Tracer(t trace.Tracer) weather.Tracer {
+	return tracer{t}
+}
+
+type tracer struct {
+	t trace.Tracer
+}
+
+func (t tracer) Start(ctx context.Context, spanName string) (context.Context, weather.Span) {
+	ctx, s := t.t.Start(ctx, spanName, trace.WithSpanKind(trace.SpanKindClient))
+	return ctx, span{s}
+}
+
+type span struct {
+	s trace.Span
+}
+
+// SetAttribute sets the attribute with the matching OpenTelemetry type. The
+// error attribute also marks the span as failed.
+func (s span) SetAttribute(key string, value interface{}) {
+	switch v := value.(type) {
+	case string:
+		s.s.SetAttributes(attribute.String(key, v))
+	case int:
+		s.s.SetAttributes(attribute.Int(key, v))
+	case int64:
+		s.s.SetAttributes(attribute.Int64(key, v))
+	case float64:
+		s.s.SetAttributes(attribute.Float64(key, v))
+	case bool:
+		s.s.SetAttributes(attribute.Bool(key, v))
+	default:
+		s.s.SetAttributes(attribute.String(key, fmt.Sprint(v)))
+	}
+	if key == "error" {
+		s.s.SetStatus(codes.Error, fmt.Sprint(value))
+	}
+}
+
+func (s span) End() {
+	s.s.End()
+}
@@ -0,0 +1,69 @@
+package otelweather
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/haleyrc/weather"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracer(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		wantStatus codes.Code
+	}{
+		{"success", http.StatusOK, codes.Unset},
+		{"failure", http.StatusInternalServerError, codes.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(`{}`))
+			}))
+			defer srv.Close()
+
+			recorder := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+			c := weather.NewClient(
+				weather.WithAPIKey("secret"),
+				weather.WithServer(srv.URL, "2.5"),
+				weather.WithTracer(Tracer(provider.Tracer("test"))),
+			)
+			c.GetCurrentWeather(context.Background(), "12345")
+
+			spans := recorder.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("got %d ended spans, want 1", len(spans))
+			}
+			span := spans[0]
+			if span.SpanKind() != trace.SpanKindClient {
+				t.Errorf("got span kind %v, want client", span.SpanKind())
+			}
+			if span.Status().Code != tt.wantStatus {
+				t.Errorf("got status %v, want %v", span.Status().Code, tt.wantStatus)
+			}
+			attrs := make(map[attribute.Key]attribute.Value)
+			for _, kv := range span.Attributes() {
+				attrs[kv.Key] = kv.Value
+			}
+			if got := attrs["http.status_code"].AsInt64(); got != int64(tt.status) {
+				t.Errorf("got http.status_code %d, want %d", got, tt.status)
+			}
+			if got := attrs["weather.endpoint"].AsString(); got != "data/2.5/weather" {
+				t.Errorf("got weather.endpoint %q", got)
+			}
+			if got := attrs["weather.params"].AsString(); got != "APPID=REDACTED&zip=12345" {
+				t.Errorf("got weather.params %q", got)
+			}
+		})
+	}
+}
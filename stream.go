@@ -0,0 +1,142 @@
+package weather
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultStreamRetry = 3 * time.Second
+
+// StreamForecast connects to a server-sent events endpoint at url and decodes
+// the data of each event as a Weather. When the connection drops, it
+// reconnects after the delay requested by the server (3s by default), sending
+// the ID of the last event received. Errors are sent on the error channel
+// without ending the stream, so callers should receive from both channels.
+// Both channels are closed once ctx is done.
+func (c Client) StreamForecast(ctx context.Context, url string) (<-chan Weather, <-chan error) {
+	weathers := make(chan Weather)
+	errs := make(chan error)
+
+	go func() {
+		defer close(weathers)
+		defer close(errs)
+
+		s := sseStream{
+			client:   c.httpClient,
+			url:      url,
+			retry:    defaultStreamRetry,
+			weathers: weathers,
+			errs:     errs,
+		}
+		for {
+			if err := s.read(ctx); err != nil && ctx.Err() == nil {
+				s.sendErr(ctx, err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(s.retry):
+			}
+		}
+	}()
+
+	return weathers, errs
+}
+
+type sseStream struct {
+	client      *http.Client
+	url         string
+	lastEventID string
+	retry       time.Duration
+	weathers    chan<- Weather
+	errs        chan<- error
+}
+
+func (s *sseStream) read(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", s.url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if s.lastEventID != "" {
+		req.Header.Set("Last-Event-ID", s.lastEventID)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("stream: unexpected status %s", resp.Status)
+	}
+
+	var data []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if len(data) > 0 {
+				s.dispatch(ctx, strings.Join(data, "\n"))
+				data = data[:0]
+			}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value := line, ""
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+		switch field {
+		case "data":
+			data = append(data, value)
+		case "id":
+			s.lastEventID = value
+		case "retry":
+			// As the spec requires, values that aren't all digits are
+			// ignored, and so is 0 to avoid reconnecting in a tight loop.
+			if ms, err := strconv.Atoi(value); err == nil && ms > 0 && isDigits(value) {
+				s.retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+func (s *sseStream) dispatch(ctx context.Context, data string) {
+	var w Weather
+	if err := json.Unmarshal([]byte(data), &w); err != nil {
+		s.sendErr(ctx, err)
+		return
+	}
+	select {
+	case s.weathers <- w:
+	case <-ctx.Done():
+	}
+}
+
+func (s *sseStream) sendErr(ctx context.Context, err error) {
+	select {
+	case s.errs <- err:
+	case <-ctx.Done():
+	}
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,92 @@
+package weather
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSSERetry(t *testing.T) {
+	tests := []struct {
+		field string
+		want  time.Duration
+	}{
+		{"retry: 1500", 1500 * time.Millisecond},
+		{"retry:250", 250 * time.Millisecond},
+		{"retry: 0", defaultStreamRetry},
+		{"retry: -5", defaultStreamRetry},
+		{"retry: +7", defaultStreamRetry},
+		{"retry: 1.5", defaultStreamRetry},
+		{"retry:", defaultStreamRetry},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			srv := httptest.NewServer(respond(tt.field + "\n\n"))
+			defer srv.Close()
+
+			s := sseStream{client: srv.Client(), url: srv.URL, retry: defaultStreamRetry}
+			if err := s.read(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if s.retry != tt.want {
+				t.Errorf("got retry %v, want %v", s.retry, tt.want)
+			}
+		})
+	}
+}
+
+func TestStreamForecastReconnects(t *testing.T) {
+	var conns int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&conns, 1)
+		if n > 1 && r.Header.Get("Last-Event-ID") != fmt.Sprint(n-1) {
+			t.Errorf("connection %d: Last-Event-ID = %q, want %d", n, r.Header.Get("Last-Event-ID"), n-1)
+		}
+		fmt.Fprintf(w, ": comment\nretry: 10\nid: %d\ndata: {\"Temperature\":\ndata: %d}\n\n", n, n)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	weathers, errs := NewClient().StreamForecast(ctx, srv.URL)
+
+	for want := 1; want <= 3; want++ {
+		select {
+		case w := <-weathers:
+			if w.Temperature != float64(want) {
+				t.Errorf("event %d: got temperature %v", want, w.Temperature)
+			}
+		case err := <-errs:
+			t.Fatalf("unexpected error: %v", err)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for event %d", want)
+		}
+	}
+	cancel()
+	for range weathers {
+	}
+}
+
+func TestStreamForecastReportsBadData(t *testing.T) {
+	srv := httptest.NewServer(respond("retry: 10\ndata: not json\n\n"))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	weathers, errs := NewClient().StreamForecast(ctx, srv.URL)
+
+	select {
+	case <-weathers:
+		t.Fatal("got a weather for invalid data")
+	case err := <-errs:
+		if err == nil {
+			t.Fatal("got nil error")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the error")
+	}
+}
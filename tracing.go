@@ -0,0 +1,64 @@
+package weather
+
+import (
+	"context"
+	"net/url"
+)
+
+// Tracer starts spans for requests made by the client. It is deliberately
+// smaller than the OpenTelemetry trace.Tracer so that the package doesn't
+// depend on OpenTelemetry. Go's method matching means an OpenTelemetry tracer
+// can't satisfy it directly; the otelweather module provides the adapter.
+type Tracer interface {
+	Start(ctx context.Context, spanName string) (context.Context, Span)
+}
+
+type Span interface {
+	SetAttribute(key string, value interface{})
+	End()
+}
+
+// WithTracer wraps each request to the API in a span with the attributes
+// weather.endpoint, weather.params (with the API key redacted),
+// http.status_code and, for failed requests, error.
+func WithTracer(tracer Tracer) Option {
+	return func(c *Client) {
+		c.tracer = tracer
+	}
+}
+
+func (c Client) startSpan(ctx context.Context, path string, queryParams url.Values) (context.Context, Span) {
+	if c.tracer == nil {
+		return ctx, nil
+	}
+
+	ctx, span := c.tracer.Start(ctx, "weather "+path)
+	span.SetAttribute("weather.endpoint", path)
+	span.SetAttribute("weather.params", redactParams(queryParams).Encode())
+	return ctx, span
+}
+
+func endSpan(span Span, status int, err error) {
+	if span == nil {
+		return
+	}
+	if status != 0 {
+		span.SetAttribute("http.status_code", status)
+	}
+	if err != nil {
+		span.SetAttribute("error", err.Error())
+	}
+	span.End()
+}
+
+// redactParams returns a copy of queryParams with the API key removed.
+func redactParams(queryParams url.Values) url.Values {
+	redacted := make(url.Values, len(queryParams))
+	for k, v := range queryParams {
+		if k == "APPID" {
+			v = []string{"REDACTED"}
+		}
+		redacted[k] = v
+	}
+	return redacted
+}
@@ -0,0 +1,73 @@
+package weather
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+type stubTracer struct {
+	spans []*stubSpan
+}
+
+func (t *stubTracer) Start(ctx context.Context, spanName string) (context.Context, Span) {
+	s := &stubSpan{name: spanName, attrs: make(map[string]interface{})}
+	t.spans = append(t.spans, s)
+	return ctx, s
+}
+
+type stubSpan struct {
+	name  string
+	attrs map[string]interface{}
+	ended int
+}
+
+func (s *stubSpan) SetAttribute(key string, value interface{}) { s.attrs[key] = value }
+func (s *stubSpan) End()                                       { s.ended++ }
+
+func TestWithTracer(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		wantError bool
+	}{
+		{"success", http.StatusOK, false},
+		{"not found", http.StatusNotFound, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracer := new(stubTracer)
+			c, done := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(`{}`))
+			}, WithTracer(tracer))
+			defer done()
+			c.GetCurrentWeather(context.Background(), "12345")
+			c.GetCurrentWeather(context.Background(), "12345")
+
+			if len(tracer.spans) != 2 {
+				t.Fatalf("got %d spans, want one per request", len(tracer.spans))
+			}
+			s := tracer.spans[0]
+			if s.ended != 1 {
+				t.Errorf("span ended %d times, want 1", s.ended)
+			}
+			if s.name != "weather data/2.5/weather" {
+				t.Errorf("got span name %q", s.name)
+			}
+			want := map[string]interface{}{
+				"weather.endpoint": "data/2.5/weather",
+				"weather.params":   "APPID=REDACTED&zip=12345",
+				"http.status_code": tt.status,
+			}
+			for k, v := range want {
+				if s.attrs[k] != v {
+					t.Errorf("attribute %s = %v, want %v", k, s.attrs[k], v)
+				}
+			}
+			if _, ok := s.attrs["error"]; ok != tt.wantError {
+				t.Errorf("error attribute set = %v, want %v", ok, tt.wantError)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package weather
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidationError lists every problem Validate found with a client's
+// configuration.
+type ValidationError struct {
+	Errors []error
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		msgs = append(msgs, err.Error())
+	}
+	return "weather: invalid configuration: " + strings.Join(msgs, "; ")
+}
+
+// Validate reports problems with the options the client was created with as
+// a *ValidationError, or returns nil if there are none.
+func (c Client) Validate() error {
+	errs := append([]error(nil), c.errs...)
+	switch c.units {
+	case Kelvin, Imperial, Metric:
+	default:
+		errs = append(errs, fmt.Errorf("unknown units %q", c.units))
+	}
+	if c.forecastTimeout < 0 {
+		errs = append(errs, fmt.Errorf("negative forecast timeout %s", c.forecastTimeout))
+	}
+	if c.maxResponseBytes <= 0 {
+		errs = append(errs, fmt.Errorf("maximum response size must be positive, got %d", c.maxResponseBytes))
+	}
+	if c.transport.IdleConnTimeout < 0 {
+		errs = append(errs, fmt.Errorf("negative idle connection timeout %s", c.transport.IdleConnTimeout))
+	}
+
+	if len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+	return nil
+}
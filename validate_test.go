@@ -0,0 +1,73 @@
+package weather
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want []string
+	}{
+		{name: "valid", opts: []Option{WithAPIKey("k")}},
+		{
+			name: "two bad options",
+			opts: []Option{WithServer("not a url", "2.5"), WithFields("Temperature", "Bogus")},
+			want: []string{`invalid server URL "not a url"`, `unknown field "Bogus"`},
+		},
+		{
+			name: "bad option and bad setting",
+			opts: []Option{WithFields("Bogus"), WithUnits("rankine"), WithMaxResponseBytes(0)},
+			want: []string{`unknown field "Bogus"`, `unknown units "rankine"`, "maximum response size"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewClient(tt.opts...).Validate()
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("got error %v, want nil", err)
+				}
+				return
+			}
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("got error %v, want *ValidationError", err)
+			}
+			if len(verr.Errors) != len(tt.want) {
+				t.Fatalf("got errors %v, want %d errors", verr.Errors, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(verr.Errors[i].Error(), want) {
+					t.Errorf("error %d = %q, want it to contain %q", i, verr.Errors[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestOptionErrorsFailRequests(t *testing.T) {
+	c := NewClient(WithAPIKey("k"), WithFields("Bogus"))
+	if _, err := c.GetCurrentWeather(context.Background(), "12345"); err == nil || !strings.Contains(err.Error(), "Bogus") {
+		t.Errorf("got error %v, want the option error", err)
+	}
+}
+
+func TestWithDoesNotShareOptionErrors(t *testing.T) {
+	base := NewClient(WithFields("A1"))
+	derived1 := base.With(WithFields("B1"))
+	derived2 := base.With(WithFields("C1"))
+	if n := len(derived1.errs); n != 2 {
+		t.Fatalf("derived1 has %d errors, want 2", n)
+	}
+	if got := derived1.errs[1].Error(); !strings.Contains(got, "B1") {
+		t.Errorf("derived1's second error is %q, want the B1 error", got)
+	}
+	if got := derived2.errs[1].Error(); !strings.Contains(got, "C1") {
+		t.Errorf("derived2's second error is %q, want the C1 error", got)
+	}
+}
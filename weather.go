@@ -1,184 +1,2815 @@
 package weather
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"math"
+	"net"
 	"net/http"
 	"net/url"
+	"os"
+	"reflect"
 	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	OpenWeatherMapHost = `https://api.openweathermap.org/`
+	OpenWeatherMapURL  = OpenWeatherMapHost + `data/2.5/`
+)
+
+// dataPath returns the path of a data endpoint for the given API version,
+// relative to the client's base URL. A version set with WithServer replaces
+// version 2.5, which the legacy endpoints use; endpoints only available in
+// other versions keep theirs.
+func (c Client) dataPath(version, endpoint string) string {
+	if c.apiVersion != "" && version == "2.5" {
+		version = c.apiVersion
+	}
+	return "data/" + version + "/" + endpoint
+}
+
+var (
+	ErrNoAPIKey     = errors.New("weather: no API key configured")
+	ErrCityNotFound = errors.New("weather: city not found")
+
+	ErrInvalidAPIKey  = errors.New("weather: invalid API key")
+	ErrInactiveAPIKey = errors.New("weather: API key not activated yet")
+)
+
+type Weather struct {
+	Date           time.Time
+	Temperature    float64
+	TemperatureMin float64
+	TemperatureMax float64
+	Humidity       float64
+
+	// FeelsLike is the apparent temperature, accounting for humidity and
+	// wind.
+	FeelsLike float64
+
+	// Pressure is the atmospheric pressure in hPa. SeaLevelPressure and
+	// GroundLevelPressure are the same as Pressure unless the API reported
+	// them separately.
+	Pressure            float64
+	SeaLevelPressure    float64
+	GroundLevelPressure float64
+
+	// PrecipitationProbability is the probability of precipitation reported
+	// by the forecast endpoint, ranging from 0 to 1.
+	PrecipitationProbability float64
+
+	// ConditionID is the OpenWeatherMap weather condition code, e.g. 500 for
+	// light rain.
+	ConditionID int
+
+	// Condition is the name of the condition, e.g. "Rain" or "Clouds".
+	Condition string
+
+	// Clouds is the cloud cover in percent.
+	Clouds float64
+
+	// Rain is the rain volume in mm over the last hour for current weather,
+	// or over the three hours up to the entry for forecasts.
+	Rain float64
+
+	Sunrise time.Time
+	Sunset  time.Time
+
+	// PartOfDay is "d" or "n" for forecast entries, and empty otherwise.
+	PartOfDay string
+
+	// WindSpeed is in meters/sec for Metric and Kelvin units and miles/hour
+	// for Imperial units.
+	WindSpeed float64
+
+	// WindDirection is the direction the wind is blowing from, in degrees.
+	WindDirection float64
+
+	// Units are the units the values were reported in.
+	Units Units
+
+	Lat float64
+	Lon float64
+}
+
+// Snapshot returns the fields of w as a flat map for structured logging. The
+// keys are date, temperature, temperature_min, temperature_max, feels_like,
+// humidity, pressure, sea_level_pressure, ground_level_pressure,
+// precipitation_probability, condition_id, condition, clouds, rain, sunrise,
+// sunset, part_of_day, wind_speed, wind_direction, units, lat and lon. Keys are
+// only ever added, never renamed or removed.
+func (w Weather) Snapshot() map[string]interface{} {
+	return map[string]interface{}{
+		"date":                      w.Date,
+		"temperature":               w.Temperature,
+		"temperature_min":           w.TemperatureMin,
+		"temperature_max":           w.TemperatureMax,
+		"feels_like":                w.FeelsLike,
+		"humidity":                  w.Humidity,
+		"pressure":                  w.Pressure,
+		"sea_level_pressure":        w.SeaLevelPressure,
+		"ground_level_pressure":     w.GroundLevelPressure,
+		"precipitation_probability": w.PrecipitationProbability,
+		"condition_id":              w.ConditionID,
+		"condition":                 w.Condition,
+		"clouds":                    w.Clouds,
+		"rain":                      w.Rain,
+		"sunrise":                   w.Sunrise,
+		"sunset":                    w.Sunset,
+		"part_of_day":               w.PartOfDay,
+		"wind_speed":                w.WindSpeed,
+		"wind_direction":            w.WindDirection,
+		"units":                     string(w.Units),
+		"lat":                       w.Lat,
+		"lon":                       w.Lon,
+	}
+}
+
+// Clone returns a copy of w that shares no memory with it, so that changes to
+// the copy never affect w. Weather holds only values for now, so this is a
+// plain copy; fields holding slices or maps must be deep-copied here.
+func (w Weather) Clone() Weather {
+	return w
+}
+
+// IsDaytime uses PartOfDay for forecast entries, and otherwise checks whether
+// Date falls between Sunrise and Sunset. Without either, it assumes daytime
+// runs from 6am to 6pm.
+func (w Weather) IsDaytime() bool {
+	switch {
+	case w.PartOfDay != "":
+		return w.PartOfDay == "d"
+	case !w.Sunrise.IsZero() && !w.Sunset.IsZero():
+		return !w.Date.Before(w.Sunrise) && w.Date.Before(w.Sunset)
+	default:
+		hour := w.Date.Hour()
+		return hour >= 6 && hour < 18
+	}
+}
+
+// DaylightDuration returns the time between Sunrise and Sunset, or 0 if
+// either is unset.
+func (w Weather) DaylightDuration() time.Duration {
+	if w.Sunrise.IsZero() || w.Sunset.IsZero() {
+		return 0
+	}
+	return w.Sunset.Sub(w.Sunrise)
+}
+
+// SolarNoon returns the midpoint of Sunrise and Sunset, or the zero time if
+// either is unset.
+func (w Weather) SolarNoon() time.Time {
+	if w.Sunrise.IsZero() || w.Sunset.IsZero() {
+		return time.Time{}
+	}
+	return w.Sunrise.Add(w.DaylightDuration() / 2)
+}
+
+const inHgPerHpa = 0.02953
+
+func (w Weather) PressureHpa() float64 {
+	return w.Pressure
+}
+
+func (w Weather) PressureInHg() float64 {
+	return w.Pressure * inHgPerHpa
+}
+
+var compassPoints = [...]string{
+	"N", "NNE", "NE", "ENE", "E", "ESE", "SE", "SSE",
+	"S", "SSW", "SW", "WSW", "W", "WNW", "NW", "NNW",
+}
+
+func (w Weather) WindDirectionCardinal() string {
+	const sector = 360.0 / float64(len(compassPoints))
+	deg := math.Mod(w.WindDirection, 360)
+	if deg < 0 {
+		deg += 360
+	}
+	i := int(math.Floor(deg/sector+0.5)) % len(compassPoints)
+	return compassPoints[i]
+}
+
+// JSONWithUnits encodes w as a JSON object using the keys of Snapshot,
+// including "units", so that the values are self-describing.
+func (w Weather) JSONWithUnits() ([]byte, error) {
+	return json.Marshal(w.Snapshot())
+}
+
+// ToOpenWeatherJSON encodes w in the schema of the API's current weather
+// response, the inverse of how the client decodes one. Values are written as
+// they are, so the client's units and humidity options apply when the output
+// is decoded. The schema has no place for PrecipitationProbability, PartOfDay
+// or Units, which are omitted.
+func (w Weather) ToOpenWeatherJSON() ([]byte, error) {
+	var resp currentWeatherResponse
+	resp.Timestamp = w.Date.Unix()
+	resp.Main.Temperature = w.Temperature
+	resp.Main.TemperatureMin = w.TemperatureMin
+	resp.Main.TemperatureMax = w.TemperatureMax
+	resp.Main.FeelsLike = w.FeelsLike
+	resp.Main.Humidity = w.Humidity
+	resp.Main.Pressure = w.Pressure
+	resp.Main.SeaLevel = w.SeaLevelPressure
+	resp.Main.GroundLevel = w.GroundLevelPressure
+	resp.Conditions = []condition{{ID: flexInt(w.ConditionID), Main: w.Condition}}
+	resp.Clouds.All = w.Clouds
+	resp.Wind = wind{Speed: w.WindSpeed, Deg: w.WindDirection}
+	resp.Rain.OneHour = w.Rain
+	resp.Coord = Coords{Lat: w.Lat, Lon: w.Lon}
+	if !w.Sunrise.IsZero() {
+		resp.Sys.Sunrise = w.Sunrise.Unix()
+	}
+	if !w.Sunset.IsZero() {
+		resp.Sys.Sunset = w.Sunset.Unix()
+	}
+	return json.Marshal(resp)
+}
+
+var beaufortNames = [...]string{
+	"Calm",
+	"Light air",
+	"Light breeze",
+	"Gentle breeze",
+	"Moderate breeze",
+	"Fresh breeze",
+	"Strong breeze",
+	"Near gale",
+	"Gale",
+	"Strong gale",
+	"Storm",
+	"Violent storm",
+	"Hurricane force",
+}
+
+// beaufortLimits are the upper bounds, in meters/sec, of Beaufort forces 0
+// through 11.
+var beaufortLimits = [...]float64{0.5, 1.6, 3.4, 5.5, 8.0, 10.8, 13.9, 17.2, 20.8, 24.5, 28.5, 32.7}
+
+func (w Weather) Beaufort() int {
+	speed := w.windSpeedMetersPerSec()
+	for force, limit := range beaufortLimits {
+		if speed < limit {
+			return force
+		}
+	}
+	return len(beaufortLimits)
+}
+
+func (w Weather) BeaufortName() string {
+	return beaufortNames[w.Beaufort()]
+}
+
+func (w Weather) windSpeedMetersPerSec() float64 {
+	if w.Units == Imperial {
+		return w.WindSpeed * metersPerSecPerMph
+	}
+	return w.WindSpeed
+}
+
+const metersPerSecPerMph = 0.44704
+
+// WindSpeedIn returns the wind speed in "mph", "kmh", "ms" (meters/sec) or
+// "knots", converting from the units w was reported in. It returns NaN for
+// any other unit.
+func (w Weather) WindSpeedIn(unit string) float64 {
+	speed := w.windSpeedMetersPerSec()
+	switch unit {
+	case "mph":
+		return speed / metersPerSecPerMph
+	case "kmh":
+		return speed * 3.6
+	case "ms":
+		return speed
+	case "knots":
+		return speed * 3600 / 1852
+	default:
+		return math.NaN()
+	}
+}
+
+func (w Weather) ConditionGroup() string {
+	switch id := w.ConditionID; {
+	case id >= 200 && id < 300:
+		return "Thunderstorm"
+	case id >= 300 && id < 400:
+		return "Drizzle"
+	case id >= 500 && id < 600:
+		return "Rain"
+	case id >= 600 && id < 700:
+		return "Snow"
+	case id >= 700 && id < 800:
+		return "Atmosphere"
+	case id == 800:
+		return "Clear"
+	case id > 800 && id < 900:
+		return "Clouds"
+	default:
+		return ""
+	}
+}
+
+// SeverityRank ranks the condition from 0 for clear skies up to 9 for a
+// tornado, for sorting by how bad the weather is. It uses the same ordering
+// as MostCommonCondition's tie-breaking.
+func (w Weather) SeverityRank() int {
+	switch id := w.ConditionID; {
+	case id == 781:
+		return conditionSeverity["Tornado"]
+	case id == 771:
+		return conditionSeverity["Squall"]
+	case id == 701, id == 721, id == 741:
+		return conditionSeverity["Mist"]
+	case id >= 700 && id < 800:
+		return conditionSeverity["Dust"]
+	default:
+		return conditionSeverity[w.ConditionGroup()]
+	}
+}
+
+func (w Weather) Emoji() string {
+	switch w.ConditionGroup() {
+	case "Thunderstorm":
+		return "⛈️"
+	case "Drizzle":
+		return "🌦️"
+	case "Rain":
+		return "🌧️"
+	case "Snow":
+		return "❄️"
+	case "Atmosphere":
+		if w.ConditionID == 781 {
+			return "🌪️"
+		}
+		return "🌫️"
+	case "Clear":
+		if w.IsDaytime() {
+			return "☀️"
+		}
+		return "🌙"
+	case "Clouds":
+		if !w.IsDaytime() || w.ConditionID >= 803 {
+			return "☁️"
+		}
+		if w.ConditionID == 801 {
+			return "🌤️"
+		}
+		return "⛅"
+	default:
+		return ""
+	}
+}
+
+// BackgroundColors maps condition groups, as returned by ConditionGroup, to
+// the hex colors BackgroundColor returns for them. The "Night" entry is used
+// instead of "Clear" and "Clouds" at night, and the "" entry for unknown
+// conditions. Change the map to use a different palette.
+var BackgroundColors = map[string]string{
+	"Thunderstorm": "#4a4e5a",
+	"Drizzle":      "#8fa3b0",
+	"Rain":         "#6b7f8e",
+	"Snow":         "#e8eef2",
+	"Atmosphere":   "#b8b5ad",
+	"Clear":        "#ffd966",
+	"Clouds":       "#b0bec5",
+	"Night":        "#1c2a4a",
+	"":             "#9e9e9e",
+}
+
+// BackgroundColor returns a hex color such as "#ffd966" suited to w's
+// condition and whether it is daytime, using BackgroundColors.
+func (w Weather) BackgroundColor() string {
+	group := w.ConditionGroup()
+	if (group == "Clear" || group == "Clouds") && !w.IsDaytime() {
+		group = "Night"
+	}
+	return BackgroundColors[group]
+}
+
+// SevereWindSpeed is the wind speed, in meters/sec, at or above which IsSevere
+// reports severe weather regardless of the condition. The default is the
+// lower bound of a gale (Beaufort force 8).
+var SevereWindSpeed = 17.2
+
+// IsSevere reports whether w is a thunderstorm, squall, tornado or extreme
+// condition, or has winds of at least SevereWindSpeed.
+func (w Weather) IsSevere() bool {
+	switch id := w.ConditionID; {
+	case id >= 200 && id < 300, id == 504, id == 771, id == 781, id >= 900 && id < 910:
+		return true
+	}
+	return w.windSpeedMetersPerSec() >= SevereWindSpeed
+}
+
+func (w Weather) PrecipitationType() string {
+	switch id := w.ConditionID; {
+	case id == 511, id >= 611 && id <= 613:
+		return "sleet"
+	case id == 615, id == 616:
+		return "mixed"
+	case id >= 600 && id < 700:
+		return "snow"
+	case id >= 200 && id < 600:
+		if id >= 210 && id <= 221 {
+			return "none"
+		}
+		return "rain"
+	default:
+		return "none"
+	}
+}
+
+// DescribeConditions summarizes w in a short phrase such as "Cold and windy
+// with light rain", for reading aloud. The temperature is described in bands
+// of degrees Celsius, converted from w.Units as by ConvertTo, so it is assumed
+// to be in °C if w.Units is unset.
+func (w Weather) DescribeConditions() string {
+	var phrase string
+	switch t := w.ConvertTo(Metric).Temperature; {
+	case t < 0:
+		phrase = "Freezing"
+	case t < 10:
+		phrase = "Cold"
+	case t < 18:
+		phrase = "Cool"
+	case t < 26:
+		phrase = "Warm"
+	default:
+		phrase = "Hot"
+	}
+
+	switch force := w.Beaufort(); {
+	case force >= 6:
+		phrase += " and windy"
+	case force >= 4:
+		phrase += " and breezy"
+	}
+
+	if sky := w.skyPhrase(); sky != "" {
+		phrase += " with " + sky
+	}
+	return phrase
+}
+
+// skyPhrase describes the condition of w for DescribeConditions, or returns
+// an empty string if the condition is unknown.
+func (w Weather) skyPhrase() string {
+	switch id := w.ConditionID; {
+	case id >= 200 && id < 300:
+		return "thunderstorms"
+	case id >= 300 && id < 400:
+		return "drizzle"
+	case id == 500, id == 520:
+		return "light rain"
+	case id >= 502 && id <= 504, id == 522:
+		return "heavy rain"
+	case id >= 500 && id < 600:
+		return w.PrecipitationType()
+	case id == 600, id == 620:
+		return "light snow"
+	case id == 602, id == 622:
+		return "heavy snow"
+	case id >= 600 && id < 700:
+		return w.PrecipitationType()
+	case id >= 700 && id < 800:
+		return strings.ToLower(w.Condition)
+	case id == 800:
+		return "clear skies"
+	case id == 801, id == 802:
+		return "some clouds"
+	case id == 803:
+		return "mostly cloudy skies"
+	case id == 804:
+		return "overcast skies"
+	default:
+		return ""
+	}
+}
+
+// Humidex assumes the temperature is in degrees Celsius (i.e. the client was
+// configured with Metric units). Humidex is only defined for temperatures of
+// 20°C and above; below that, the temperature is returned unchanged.
+func (w Weather) Humidex() float64 {
+	if w.Temperature < 20 || w.Humidity <= 0 {
+		return w.Temperature
+	}
+	dewPoint := dewPoint(w.Temperature, w.Humidity)
+	e := 6.11 * math.Exp(5417.7530*(1/273.16-1/(273.15+dewPoint)))
+	return w.Temperature + 0.5555*(e-10)
+}
+
+// VaporPressureDeficit returns the vapor pressure deficit in kPa, computed as
+// the saturation vapor pressure (Tetens equation) multiplied by one minus the
+// relative humidity:
+//
+//	VPD = 0.6108 * exp(17.27*T / (T+237.3)) * (1 - RH/100)
+//
+// It assumes the temperature is in degrees Celsius (i.e. the client was
+// configured with Metric units).
+func (w Weather) VaporPressureDeficit() float64 {
+	svp := 0.6108 * math.Exp(17.27*w.Temperature/(w.Temperature+237.3))
+	return svp * (1 - w.Humidity/100)
+}
+
+// ComfortIndex rates how comfortable conditions feel from 0 (very
+// uncomfortable) to 100 (ideal). It starts at 100 and subtracts penalties for
+// conditions outside common comfort ranges, loosely based on ASHRAE
+// Standard 55:
+//
+//   - 5 points per °C of feels-like temperature outside 20–24°C
+//   - 1 point per percent of humidity outside 30–60%
+//   - 4 points per m/s of wind above 5 m/s
+//
+// Temperatures are converted from w.Units; if w.Units is unset they are
+// assumed to be in °C. A zero FeelsLike is taken to mean it wasn't reported,
+// as with other zero fields, so the actual temperature is used instead. That
+// includes a genuine feels-like reading of exactly 0 degrees.
+func (w Weather) ComfortIndex() float64 {
+	if w.FeelsLike == 0 {
+		w.FeelsLike = w.Temperature
+	}
+	apparent := w.ConvertTo(Metric).FeelsLike
+
+	outside := func(v, lo, hi float64) float64 {
+		return math.Max(0, math.Max(lo-v, v-hi))
+	}
+	penalty := 5*outside(apparent, 20, 24) +
+		outside(w.Humidity, 30, 60) +
+		4*math.Max(0, w.windSpeedMetersPerSec()-5)
+	return math.Max(0, 100-penalty)
+}
+
+// WetBulbTemperature estimates the wet-bulb temperature using Stull's (2011)
+// empirical formula. It assumes the temperature is in degrees Celsius (i.e. the
+// client was configured with Metric units) and is accurate to within about
+// 1°C for relative humidities of 5–99% and temperatures of -20–50°C.
+func (w Weather) WetBulbTemperature() float64 {
+	t, rh := w.Temperature, w.Humidity
+	return t*math.Atan(0.151977*math.Sqrt(rh+8.313659)) +
+		math.Atan(t+rh) - math.Atan(rh-1.676331) +
+		0.00391838*math.Pow(rh, 1.5)*math.Atan(0.023101*rh) -
+		4.686035
+}
+
+// dewPoint uses the Magnus approximation to compute the dew point in degrees
+// Celsius from a temperature in degrees Celsius and a relative humidity in
+// percent.
+func dewPoint(temp, humidity float64) float64 {
+	const b, c = 17.62, 243.12
+	gamma := math.Log(humidity/100) + b*temp/(c+temp)
+	return c * gamma / (b - gamma)
+}
+
+type Units string
+
+const (
+	Kelvin   Units = "kelvin"
+	Imperial Units = "imperial"
+	Metric   Units = "metric"
 )
 
-const OpenWeatherMapURL = `https://api.openweathermap.org/data/2.5/`
+// Locator identifies a location in one of the forms accepted by the
+// OpenWeatherMap API.
+type Locator interface {
+	setParams(params url.Values)
+}
+
+type ZipCode string
+
+func (z ZipCode) setParams(params url.Values) {
+	params.Set("zip", string(z))
+}
+
+type CityName string
+
+func (n CityName) setParams(params url.Values) {
+	params.Set("q", string(n))
+}
+
+type CityID int
+
+func (id CityID) setParams(params url.Values) {
+	params.Set("id", strconv.Itoa(int(id)))
+}
+
+type Coords struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+func (c Coords) setParams(params url.Values) {
+	params.Set("lat", strconv.FormatFloat(c.Lat, 'f', -1, 64))
+	params.Set("lon", strconv.FormatFloat(c.Lon, 'f', -1, 64))
+}
+
+// ConvertTo returns a copy of w with its values converted from w.Units to
+// target. If w.Units is not set, w is returned unchanged.
+func (w Weather) ConvertTo(target Units) Weather {
+	if w.Units == "" || w.Units == target {
+		return w
+	}
+
+	from := w.Units
+	w.Temperature = convertTemperature(w.Temperature, from, target)
+	w.TemperatureMin = convertTemperature(w.TemperatureMin, from, target)
+	w.TemperatureMax = convertTemperature(w.TemperatureMax, from, target)
+	w.FeelsLike = convertTemperature(w.FeelsLike, from, target)
+	w.WindSpeed = convertSpeed(w.WindSpeed, from, target)
+	w.Units = target
+	return w
+}
+
+// RoundTemperatures returns a copy of w with every temperature field rounded
+// to the nearest multiple of step. A step that is not positive leaves the
+// temperatures unchanged.
+func (w Weather) RoundTemperatures(step float64) Weather {
+	if step <= 0 {
+		return w
+	}
+	round := func(t float64) float64 {
+		return math.Round(t/step) * step
+	}
+	w.Temperature = round(w.Temperature)
+	w.TemperatureMin = round(w.TemperatureMin)
+	w.TemperatureMax = round(w.TemperatureMax)
+	w.FeelsLike = round(w.FeelsLike)
+	return w
+}
+
+func (w Weather) ToImperial() Weather {
+	return w.ConvertTo(Imperial)
+}
+
+func (w Weather) ToMetric() Weather {
+	return w.ConvertTo(Metric)
+}
+
+func convertTemperature(t float64, from, to Units) float64 {
+	var kelvin float64
+	switch from {
+	case Metric:
+		kelvin = t + 273.15
+	case Imperial:
+		kelvin = (t-32)*5/9 + 273.15
+	default:
+		kelvin = t
+	}
+
+	switch to {
+	case Metric:
+		return kelvin - 273.15
+	case Imperial:
+		return (kelvin-273.15)*9/5 + 32
+	default:
+		return kelvin
+	}
+}
+
+func convertSpeed(s float64, from, to Units) float64 {
+	switch {
+	case from == Imperial && to != Imperial:
+		return s * metersPerSecPerMph
+	case from != Imperial && to == Imperial:
+		return s / metersPerSecPerMph
+	default:
+		return s
+	}
+}
+
+type Option func(c *Client)
+
+func WithAPIKey(k string) Option {
+	return func(c *Client) {
+		c.apiKey = k
+	}
+}
+
+const DefaultAPIKeyEnvVar = "OPENWEATHERMAP_APIKEY"
+
+// WithAPIKeyFromEnv reads the API key from the environment variable varName,
+// or from DefaultAPIKeyEnvVar if varName is empty. If the variable is unset,
+// requests fail with ErrNoAPIKey.
+func WithAPIKeyFromEnv(varName string) Option {
+	if varName == "" {
+		varName = DefaultAPIKeyEnvVar
+	}
+	return func(c *Client) {
+		c.apiKey = os.Getenv(varName)
+	}
+}
+
+type apiKeyContextKey struct{}
+
+// ContextWithAPIKey returns a copy of ctx carrying an API key that requests
+// made with it use instead of the client's configured key.
+func ContextWithAPIKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, apiKeyContextKey{}, key)
+}
+
+func WithUnits(units Units) Option {
+	return func(c *Client) {
+		c.units = units
+	}
+}
+
+// WithUnitsAlias sends apiValue as the units parameter when the client uses
+// u, for servers that name units differently from OpenWeatherMap. An alias
+// for Kelvin is sent too, though Kelvin is normally sent as no parameter.
+func WithUnitsAlias(u Units, apiValue string) Option {
+	return func(c *Client) {
+		aliases := make(map[Units]string, len(c.unitsAliases)+1)
+		for k, v := range c.unitsAliases {
+			aliases[k] = v
+		}
+		aliases[u] = apiValue
+		c.unitsAliases = aliases
+	}
+}
+
+// WithFractionalHumidity reports humidity as a fraction from 0 to 1 instead of
+// the default percentage from 0 to 100. Humidex, VaporPressureDeficit,
+// WetBulbTemperature and ComfortIndex expect a percentage and give wrong
+// results for fractional humidity, so multiply Humidity by 100 before calling
+// them. The maxHumidity of Forecast.ComfortableHours must be a fraction too.
+func WithFractionalHumidity() Option {
+	return func(c *Client) {
+		c.fractionalHumidity = true
+	}
+}
+
+// WithFields makes the client return only the named Weather fields, such as
+// "Date" and "Temperature", leaving the others zero. It applies to forecasts
+// and current weather alike. Responses are still decoded in full and the
+// other fields cleared afterwards, so it filters what callers see rather than
+// saving memory. By default every field is populated. Naming a field that
+// Weather doesn't have makes requests fail with an error describing it.
+func WithFields(fields ...string) Option {
+	return func(c *Client) {
+		set := make(map[string]bool, len(fields))
+		for _, name := range fields {
+			if _, ok := reflect.TypeOf(Weather{}).FieldByName(name); !ok {
+				c.addErr(fmt.Errorf("weather: unknown field %q", name))
+				return
+			}
+			set[name] = true
+		}
+		c.fields = set
+	}
+}
+
+// WithClock makes the client use now instead of time.Now to tell the time,
+// for methods that depend on it such as GetForecastFor and GetTomorrow.
+func WithClock(now func() time.Time) Option {
+	return func(c *Client) {
+		c.now = now
+	}
+}
+
+// WithDefaultLocation sets the zip code used when a method is called with an
+// empty zip code.
+func WithDefaultLocation(zip string) Option {
+	return func(c *Client) {
+		c.defaultZip = zip
+	}
+}
+
+// WithForecastTimeout sets the timeout for forecast requests, which return
+// larger responses than other requests. If unset, forecast requests use the
+// same timeout as every other request.
+func WithForecastTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.forecastTimeout = d
+	}
+}
+
+const DefaultMaxResponseBytes = 10 << 20
+
+// WithMaxResponseBytes limits the size of response bodies to n bytes. Larger
+// responses fail with a *ResponseTooLargeError. The default limit is
+// DefaultMaxResponseBytes.
+func WithMaxResponseBytes(n int64) Option {
+	return func(c *Client) {
+		c.maxResponseBytes = n
+	}
+}
+
+// WithRequestCoalescing makes concurrent identical requests share a single
+// call to the API. The shared call uses the context of whichever caller
+// started it, so cancelling that context fails every caller waiting on it.
+func WithRequestCoalescing() Option {
+	return func(c *Client) {
+		c.flights = new(singleflight.Group)
+	}
+}
+
+// WithForceAttemptHTTP2 controls whether the client's transport attempts
+// HTTP/2. It is enabled by default, as in http.DefaultTransport.
+func WithForceAttemptHTTP2(force bool) Option {
+	return func(c *Client) {
+		c.configureTransport(func(t *http.Transport) {
+			t.ForceAttemptHTTP2 = force
+		})
+	}
+}
+
+// WithIdleConnTimeout sets how long idle connections are kept open for
+// reuse. The default is 90 seconds, as in http.DefaultTransport.
+func WithIdleConnTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.configureTransport(func(t *http.Transport) {
+			t.IdleConnTimeout = d
+		})
+	}
+}
+
+// WithServer sends requests to an OpenWeatherMap-compatible server at
+// baseURL, using version for the 2.5 data endpoints, so that a request for
+// current weather goes to baseURL + "data/" + version + "/weather". Endpoints
+// of other API versions, such as GetDaySummary's One Call 3.0 endpoint, keep
+// their versions. If baseURL is not an absolute URL, requests fail with an
+// error describing it.
+func WithServer(baseURL, version string) Option {
+	return func(c *Client) {
+		u, err := url.Parse(baseURL)
+		if err != nil {
+			c.addErr(fmt.Errorf("weather: invalid server URL: %w", err))
+			return
+		}
+		if !u.IsAbs() || u.Host == "" {
+			c.addErr(fmt.Errorf("weather: invalid server URL %q: must be absolute", baseURL))
+			return
+		}
+		if !strings.HasSuffix(baseURL, "/") {
+			baseURL += "/"
+		}
+		c.baseURL = baseURL
+		c.apiVersion = version
+	}
+}
+
+// WithDialer makes the client's transport open connections with dialer. For
+// example, a dialer whose LocalAddr is an IPv4 address only connects over
+// IPv4.
+func WithDialer(dialer *net.Dialer) Option {
+	return func(c *Client) {
+		c.configureTransport(func(t *http.Transport) {
+			t.DialContext = dialer.DialContext
+		})
+	}
+}
+
+// WithProxyFromEnvironment makes the client's transport choose a proxy using
+// http.ProxyFromEnvironment. The default transport, cloned from
+// http.DefaultTransport, already does this; the option states it explicitly.
+func WithProxyFromEnvironment() Option {
+	return func(c *Client) {
+		c.configureTransport(func(t *http.Transport) {
+			t.Proxy = http.ProxyFromEnvironment
+		})
+	}
+}
+
+type ResponseTooLargeError struct {
+	Limit int64
+}
+
+func (e *ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("weather: response exceeds %d bytes", e.Limit)
+}
+
+type Client struct {
+	errs               []error
+	baseURL            string
+	apiVersion         string
+	apiKey             string
+	units              Units
+	unitsAliases       map[Units]string
+	defaultZip         string
+	fractionalHumidity bool
+	fields             map[string]bool
+	httpClient         *http.Client
+	transport          *http.Transport
+	timeout            time.Duration
+	forecastTimeout    time.Duration
+	maxResponseBytes   int64
+	flights            *singleflight.Group
+	metrics            *metrics
+	latencies          *latencyTracker
+	tracer             Tracer
+	fixtureDir         string
+	now                func() time.Time
+}
+
+func NewClient(opts ...Option) Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	c := Client{
+		baseURL:          OpenWeatherMapHost,
+		units:            Kelvin,
+		httpClient:       &http.Client{Transport: transport},
+		transport:        transport,
+		timeout:          5 * time.Second,
+		maxResponseBytes: DefaultMaxResponseBytes,
+		now:              time.Now,
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// With returns a copy of c with opts applied. Unless opts replace them, the
+// copy shares with c its HTTP client, the requests in flight coalesced by
+// WithRequestCoalescing, the counts kept by WithMetrics and the latencies
+// tracked by WithAdaptiveTimeout. Requests are only coalesced with identical
+// requests to the same server.
+func (c Client) With(opts ...Option) Client {
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// addErr records a problem with an option, which makes every request fail
+// and is reported by Validate. It copies the slice so that clients derived
+// with With don't add to each other's errors.
+func (c *Client) addErr(err error) {
+	c.errs = append(c.errs[:len(c.errs):len(c.errs)], err)
+}
+
+// configureTransport applies fn to a copy of the client's transport, so that
+// clients derived with With don't modify each other's transports.
+func (c *Client) configureTransport(fn func(t *http.Transport)) {
+	t := c.transport.Clone()
+	fn(t)
+	c.transport = t
+	c.httpClient = &http.Client{Transport: t}
+}
+
+// Now returns the current time according to the client's clock, which is
+// time.Now unless set with WithClock.
+func (c Client) Now() time.Time {
+	return c.now()
+}
+
+func (c Client) zip(zip string) string {
+	if zip == "" {
+		return c.defaultZip
+	}
+	return zip
+}
+
+func (c Client) humidity(percent float64) float64 {
+	if c.fractionalHumidity {
+		return percent / 100
+	}
+	return percent
+}
+
+// selectFields zeroes the fields of w not chosen with WithFields.
+func (c Client) selectFields(w *Weather) {
+	if c.fields == nil {
+		return
+	}
+	v := reflect.ValueOf(w).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if !c.fields[v.Type().Field(i).Name] {
+			v.Field(i).Set(reflect.Zero(v.Field(i).Type()))
+		}
+	}
+}
+
+func (c Client) requestTimeout(path string) time.Duration {
+	if c.latencies != nil {
+		return c.latencies.timeout()
+	}
+	if strings.HasSuffix(path, "/forecast") && c.forecastTimeout > 0 {
+		return c.forecastTimeout
+	}
+	return c.timeout
+}
+
+// makeRequest requests path, which is relative to the client's base URL and
+// includes the API version, and decodes the response into dest.
+func (c Client) makeRequest(ctx context.Context, dest interface{}, path string, queryParams url.Values) error {
+	b, err := c.fetch(ctx, path, queryParams)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, dest)
+}
+
+func (c Client) fetch(ctx context.Context, path string, queryParams url.Values) ([]byte, error) {
+	if err := c.setCommonParams(ctx, queryParams); err != nil {
+		return nil, err
+	}
+
+	if c.flights == nil {
+		return c.get(ctx, path, queryParams)
+	}
+	// The path includes the API version, and the parameters include the API
+	// key, so the key only matches requests that would get the same response.
+	key := c.baseURL + path + "?" + queryParams.Encode()
+	v, err, _ := c.flights.Do(key, func() (interface{}, error) {
+		return c.get(ctx, path, queryParams)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]byte), nil
+}
+
+// setCommonParams adds the API key and units to queryParams.
+func (c Client) setCommonParams(ctx context.Context, queryParams url.Values) error {
+	if len(c.errs) > 0 {
+		return c.errs[0]
+	}
+
+	apiKey := c.apiKey
+	if key, ok := ctx.Value(apiKeyContextKey{}).(string); ok && key != "" {
+		apiKey = key
+	}
+	if apiKey == "" {
+		return ErrNoAPIKey
+	}
+
+	queryParams.Set("APPID", apiKey)
+	if alias, ok := c.unitsAliases[c.units]; ok {
+		queryParams.Set("units", alias)
+	} else if c.units != Kelvin {
+		queryParams.Set("units", string(c.units))
+	}
+	return nil
+}
+
+func (c Client) get(ctx context.Context, path string, queryParams url.Values) ([]byte, error) {
+	var b []byte
+	err := c.do(ctx, path, queryParams, func(body io.Reader) error {
+		var err error
+		b, err = ioutil.ReadAll(body)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// do makes a single request to the API, tracing it, recording it in the
+// metrics and saving the body as a fixture if enabled. read is called with
+// the body of a successful response, which fails with a
+// *ResponseTooLargeError once it exceeds the client's limit.
+func (c Client) do(ctx context.Context, path string, queryParams url.Values, read func(body io.Reader) error) error {
+	var fixture *bytes.Buffer
+	if c.fixtureDir != "" {
+		fixture = new(bytes.Buffer)
+		next := read
+		read = func(body io.Reader) error {
+			return next(io.TeeReader(body, fixture))
+		}
+	}
+
+	ctx, span := c.startSpan(ctx, path, queryParams)
+	start := time.Now()
+	status, err := c.roundTrip(ctx, path, queryParams, read)
+	c.metrics.observe(time.Since(start), err)
+	endSpan(span, status, err)
+	if err != nil {
+		return err
+	}
+	if fixture != nil {
+		return c.recordFixture(path, queryParams, fixture.Bytes())
+	}
+	return nil
+}
+
+// roundTrip makes a single request to the API, calling read with the body of
+// a successful response. It returns the HTTP status code, which is 0 if no
+// response was received.
+func (c Client) roundTrip(ctx context.Context, path string, queryParams url.Values, read func(body io.Reader) error) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.requestTimeout(path))
+	defer cancel()
+
+	start := time.Now()
+	defer func() { c.latencies.observe(time.Since(start)) }()
+
+	resp, err := c.send(ctx, path, queryParams)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body := &limitedReader{r: io.LimitReader(resp.Body, c.maxResponseBytes+1), limit: c.maxResponseBytes}
+	if resp.StatusCode != http.StatusOK {
+		b, err := ioutil.ReadAll(body)
+		if err != nil {
+			return resp.StatusCode, err
+		}
+		return resp.StatusCode, statusError(resp.StatusCode, b)
+	}
+	return resp.StatusCode, read(body)
+}
+
+// limitedReader fails with a *ResponseTooLargeError once more than limit
+// bytes have been read from r.
+type limitedReader struct {
+	r     io.Reader
+	n     int64
+	limit int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.n += int64(n)
+	if l.n > l.limit {
+		return n, &ResponseTooLargeError{Limit: l.limit}
+	}
+	return n, err
+}
+
+func (c Client) send(ctx context.Context, path string, queryParams url.Values) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.URL.RawQuery = queryParams.Encode()
+	return c.httpClient.Do(req)
+}
+
+// statusError returns the error for a non-200 response with the given body.
+func statusError(status int, body []byte) error {
+	switch status {
+	case http.StatusNotFound:
+		return ErrCityNotFound
+	case http.StatusUnauthorized:
+		return apiKeyError(body)
+	default:
+		return errors.New(string(body))
+	}
+}
+
+// inactiveAPIKeyMessage is the 401 message for keys that have not been
+// activated yet, worded as in https://openweathermap.org/faq#error401. Wrong
+// keys get "Invalid API key. Please see
+// https://openweathermap.org/faq#error401 for more info." instead.
+const inactiveAPIKeyMessage = "Your API key is not activated yet. Within the next couple of hours, it will be activated and ready to use."
+
+// apiKeyError classifies the body of a 401 response. A message exactly
+// matching inactiveAPIKeyMessage is reported as ErrInactiveAPIKey and
+// everything else as ErrInvalidAPIKey, wrapped with the message from the API.
+func apiKeyError(body []byte) error {
+	var resp struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil || resp.Message == "" {
+		resp.Message = string(body)
+	}
+
+	if strings.TrimSpace(resp.Message) == inactiveAPIKeyMessage {
+		return fmt.Errorf("%w: %s", ErrInactiveAPIKey, resp.Message)
+	}
+	return fmt.Errorf("%w: %s", ErrInvalidAPIKey, resp.Message)
+}
+
+type condition struct {
+	ID   flexInt `json:"id"`
+	Main string  `json:"main"`
+}
+
+// flexInt is an int that can also be decoded from a JSON number with a
+// fractional part, such as 500.0, which the API occasionally sends for
+// integer fields.
+type flexInt int
+
+func (i *flexInt) UnmarshalJSON(b []byte) error {
+	var f float64
+	if err := json.Unmarshal(b, &f); err != nil {
+		return err
+	}
+	*i = flexInt(f)
+	return nil
+}
+
+// primaryCondition returns the primary (first) condition reported by the API,
+// or the zero condition if there are none.
+func primaryCondition(conds []condition) condition {
+	if len(conds) == 0 {
+		return condition{}
+	}
+	return conds[0]
+}
+
+type clouds struct {
+	All float64 `json:"all"`
+}
+
+type wind struct {
+	Speed float64 `json:"speed"`
+	Deg   float64 `json:"deg"`
+}
+
+// orDefault returns v, or def if v is zero because the API omitted it.
+func orDefault(v, def float64) float64 {
+	if v == 0 {
+		return def
+	}
+	return v
+}
+
+// unixTime converts a Unix timestamp from the API to a time.Time, treating 0
+// as absent.
+func unixTime(ts int64) time.Time {
+	if ts == 0 {
+		return time.Time{}
+	}
+	return time.Unix(ts, 0)
+}
+
+func (c Client) GetForecast(ctx context.Context, zip string) (Forecast, error) {
+	return c.GetForecastAt(ctx, ZipCode(c.zip(zip)))
+}
+
+func (c Client) GetForecastAt(ctx context.Context, loc Locator) (Forecast, error) {
+	var resp struct {
+		List []struct {
+			Timestamp int64 `json:"dt"`
+			Main      struct {
+				Temperature    float64 `json:"temp"`
+				TemperatureMin float64 `json:"temp_min"`
+				TemperatureMax float64 `json:"temp_max"`
+				FeelsLike      float64 `json:"feels_like"`
+				Humidity       float64 `json:"humidity"`
+				Pressure       float64 `json:"pressure"`
+				SeaLevel       float64 `json:"sea_level"`
+				GroundLevel    float64 `json:"grnd_level"`
+			} `json:"main"`
+			Conditions []condition `json:"weather"`
+			Clouds     clouds      `json:"clouds"`
+			Wind       wind        `json:"wind"`
+			Rain       struct {
+				ThreeHour float64 `json:"3h"`
+			} `json:"rain"`
+			PrecipitationProbability float64 `json:"pop"`
+			Sys                      struct {
+				PartOfDay string `json:"pod"`
+			} `json:"sys"`
+		} `json:"list"`
+		City struct {
+			Coord   Coords `json:"coord"`
+			Sunrise int64  `json:"sunrise"`
+			Sunset  int64  `json:"sunset"`
+		} `json:"city"`
+	}
+
+	params := make(url.Values)
+	loc.setParams(params)
+	if err := c.makeRequest(ctx, &resp, c.dataPath("2.5", "forecast"), params); err != nil {
+		return nil, err
+	}
+
+	weathers := make(Forecast, 0, len(resp.List))
+	for _, w := range resp.List {
+		weather := Weather{
+			Date:                time.Unix(w.Timestamp, 0),
+			Humidity:            c.humidity(w.Main.Humidity),
+			Pressure:            w.Main.Pressure,
+			SeaLevelPressure:    orDefault(w.Main.SeaLevel, w.Main.Pressure),
+			GroundLevelPressure: orDefault(w.Main.GroundLevel, w.Main.Pressure),
+			Temperature:         w.Main.Temperature,
+			TemperatureMin:      w.Main.TemperatureMin,
+			TemperatureMax:      w.Main.TemperatureMax,
+			FeelsLike:           w.Main.FeelsLike,
+
+			PrecipitationProbability: w.PrecipitationProbability,
+			ConditionID:              int(primaryCondition(w.Conditions).ID),
+			Condition:                primaryCondition(w.Conditions).Main,
+			Clouds:                   w.Clouds.All,
+			Rain:                     w.Rain.ThreeHour,
+			Sunrise:                  unixTime(resp.City.Sunrise),
+			Sunset:                   unixTime(resp.City.Sunset),
+			PartOfDay:                w.Sys.PartOfDay,
+			WindSpeed:                w.Wind.Speed,
+			WindDirection:            w.Wind.Deg,
+			Units:                    c.units,
+			Lat:                      resp.City.Coord.Lat,
+			Lon:                      resp.City.Coord.Lon,
+		}
+		c.selectFields(&weather)
+		weathers = append(weathers, weather)
+	}
+
+	return weathers, nil
+}
+
+// GetForecastResilient returns the forecast for zip, falling back to the
+// forecast at lat and lon if the zip code is not found.
+func (c Client) GetForecastResilient(ctx context.Context, zip string, lat, lon float64) (Forecast, error) {
+	f, err := c.GetForecast(ctx, zip)
+	if errors.Is(err, ErrCityNotFound) {
+		return c.GetForecastAt(ctx, Coords{Lat: lat, Lon: lon})
+	}
+	return f, err
+}
+
+// GetForecastFor returns the forecast for zip, trimmed to entries no later
+// than horizon from now.
+func (c Client) GetForecastFor(ctx context.Context, zip string, horizon time.Duration) (Forecast, error) {
+	f, err := c.GetForecast(ctx, zip)
+	if err != nil {
+		return nil, err
+	}
+
+	end := c.Now().Add(horizon)
+	trimmed := make(Forecast, 0, len(f))
+	for _, w := range f {
+		if !w.Date.After(end) {
+			trimmed = append(trimmed, w)
+		}
+	}
+	return trimmed, nil
+}
+
+// NextForecast returns the first forecast entry for zip at or after the
+// client's current time. ok is false if the forecast has no such entry.
+func (c Client) NextForecast(ctx context.Context, zip string) (w Weather, ok bool, err error) {
+	f, err := c.GetForecast(ctx, zip)
+	if err != nil {
+		return Weather{}, false, err
+	}
+	next := f.AsOf(c.Now())
+	if len(next) == 0 {
+		return Weather{}, false, nil
+	}
+	return next[0], true, nil
+}
+
+// GetTomorrow returns the daily forecast entry for zip for the day after the
+// client's current time, as computed by Forecast.Tomorrow.
+func (c Client) GetTomorrow(ctx context.Context, zip string) (day Weather, ok bool, err error) {
+	f, err := c.GetForecast(ctx, zip)
+	if err != nil {
+		return Weather{}, false, err
+	}
+	day, ok = f.Tomorrow(c.Now())
+	return day, ok, nil
+}
+
+func (c Client) GetCurrentWeather(ctx context.Context, zip string) (Weather, error) {
+	return c.GetCurrentWeatherAt(ctx, ZipCode(c.zip(zip)))
+}
+
+// GetCurrentWeatherTimeout is like GetCurrentWeather, but gives up after d.
+func (c Client) GetCurrentWeatherTimeout(ctx context.Context, zip string, d time.Duration) (Weather, error) {
+	ctx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+	return c.GetCurrentWeather(ctx, zip)
+}
+
+func (c Client) GetCurrentWeatherAt(ctx context.Context, loc Locator) (Weather, error) {
+	var resp currentWeatherResponse
+	params := make(url.Values)
+	loc.setParams(params)
+	if err := c.makeRequest(ctx, &resp, c.dataPath("2.5", "weather"), params); err != nil {
+		return Weather{}, err
+	}
+	return c.currentWeather(resp), nil
+}
+
+type currentWeatherResponse struct {
+	ID        flexInt `json:"id"`
+	Name      string  `json:"name"`
+	Timestamp int64   `json:"dt"`
+	Main      struct {
+		Temperature    float64 `json:"temp"`
+		TemperatureMin float64 `json:"temp_min"`
+		TemperatureMax float64 `json:"temp_max"`
+		FeelsLike      float64 `json:"feels_like"`
+		Humidity       float64 `json:"humidity"`
+		Pressure       float64 `json:"pressure"`
+		SeaLevel       float64 `json:"sea_level"`
+		GroundLevel    float64 `json:"grnd_level"`
+	} `json:"main"`
+	Conditions []condition `json:"weather"`
+	Clouds     clouds      `json:"clouds"`
+	Wind       wind        `json:"wind"`
+	Rain       struct {
+		OneHour float64 `json:"1h"`
+	} `json:"rain"`
+	Coord Coords `json:"coord"`
+	Sys   struct {
+		Country string `json:"country"`
+		Sunrise int64  `json:"sunrise"`
+		Sunset  int64  `json:"sunset"`
+	} `json:"sys"`
+}
+
+func (c Client) currentWeather(resp currentWeatherResponse) Weather {
+	w := Weather{
+		Date:                time.Unix(resp.Timestamp, 0),
+		Humidity:            c.humidity(resp.Main.Humidity),
+		Pressure:            resp.Main.Pressure,
+		SeaLevelPressure:    orDefault(resp.Main.SeaLevel, resp.Main.Pressure),
+		GroundLevelPressure: orDefault(resp.Main.GroundLevel, resp.Main.Pressure),
+		Temperature:         resp.Main.Temperature,
+		TemperatureMax:      resp.Main.TemperatureMax,
+		FeelsLike:           resp.Main.FeelsLike,
+		TemperatureMin:      resp.Main.TemperatureMin,
+		ConditionID:         int(primaryCondition(resp.Conditions).ID),
+		Condition:           primaryCondition(resp.Conditions).Main,
+		Clouds:              resp.Clouds.All,
+		Rain:                resp.Rain.OneHour,
+		Sunrise:             unixTime(resp.Sys.Sunrise),
+		Sunset:              unixTime(resp.Sys.Sunset),
+		WindSpeed:           resp.Wind.Speed,
+		WindDirection:       resp.Wind.Deg,
+		Units:               c.units,
+		Lat:                 resp.Coord.Lat,
+		Lon:                 resp.Coord.Lon,
+	}
+	c.selectFields(&w)
+	return w
+}
+
+type BoundingBox struct {
+	LonLeft   float64
+	LatBottom float64
+	LonRight  float64
+	LatTop    float64
+}
+
+// StreamWeatherInBox fetches the current weather for the cities within box
+// at the given map zoom level and calls fn with each city's weather as it is
+// decoded, rather than decoding the whole response first. If fn returns an
+// error, streaming stops and that error is returned. The response is limited
+// in size like any other, failing with a *ResponseTooLargeError once it
+// exceeds the limit.
+func (c Client) StreamWeatherInBox(ctx context.Context, box BoundingBox, zoom int, fn func(Weather) error) error {
+	params := make(url.Values)
+	params.Set("bbox", strings.Join([]string{
+		strconv.FormatFloat(box.LonLeft, 'f', -1, 64),
+		strconv.FormatFloat(box.LatBottom, 'f', -1, 64),
+		strconv.FormatFloat(box.LonRight, 'f', -1, 64),
+		strconv.FormatFloat(box.LatTop, 'f', -1, 64),
+		strconv.Itoa(zoom),
+	}, ","))
+	if err := c.setCommonParams(ctx, params); err != nil {
+		return err
+	}
+
+	return c.do(ctx, c.dataPath("2.5", "box/city"), params, func(body io.Reader) error {
+		return c.decodeCityList(body, fn)
+	})
+}
+
+// decodeCityList decodes the current weather responses in the "list" array
+// of body one at a time, calling fn with each.
+func (c Client) decodeCityList(body io.Reader, fn func(Weather) error) error {
+	dec := json.NewDecoder(body)
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if key != "list" {
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := expectDelim(dec, '['); err != nil {
+			return err
+		}
+		for dec.More() {
+			var r currentWeatherResponse
+			if err := dec.Decode(&r); err != nil {
+				return err
+			}
+			if err := fn(c.currentWeather(r)); err != nil {
+				return err
+			}
+		}
+		return expectDelim(dec, ']')
+	}
+	return nil
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != want {
+		return fmt.Errorf("weather: unexpected JSON token %v, expected %v", tok, want)
+	}
+	return nil
+}
+
+type City struct {
+	ID      int
+	Name    string
+	Country string
+}
+
+type CityWeather struct {
+	City
+	Weather
+}
+
+const maxFindLimit = 50
+
+// FindCities searches for cities matching query, returning at most limit
+// results along with their current weather. The API accepts limits from 1 to
+// 50.
+func (c Client) FindCities(ctx context.Context, query string, limit int) ([]CityWeather, error) {
+	if limit < 1 || limit > maxFindLimit {
+		return nil, fmt.Errorf("weather: limit must be between 1 and %d, got %d", maxFindLimit, limit)
+	}
+
+	var resp struct {
+		List []currentWeatherResponse `json:"list"`
+	}
+	params := make(url.Values)
+	params.Set("q", query)
+	params.Set("cnt", strconv.Itoa(limit))
+	if err := c.makeRequest(ctx, &resp, c.dataPath("2.5", "find"), params); err != nil {
+		return nil, err
+	}
+
+	cities := make([]CityWeather, 0, len(resp.List))
+	for _, r := range resp.List {
+		cities = append(cities, CityWeather{
+			City: City{
+				ID:      int(r.ID),
+				Name:    r.Name,
+				Country: r.Sys.Country,
+			},
+			Weather: c.currentWeather(r),
+		})
+	}
+	return cities, nil
+}
+
+// Location is a place resolved by geocoding. Its embedded Coords make it a
+// Locator, so it can be passed to the methods that take one.
+type Location struct {
+	Coords
+	Zip     string
+	Name    string
+	Country string
+}
+
+// GeocodeZip resolves zip within country, an ISO 3166 country code, to its
+// location. The API assumes the United States if country is empty. It returns
+// ErrCityNotFound if no location has that zip code.
+func (c Client) GeocodeZip(ctx context.Context, zip, country string) (Location, error) {
+	var resp struct {
+		Zip     string  `json:"zip"`
+		Name    string  `json:"name"`
+		Lat     float64 `json:"lat"`
+		Lon     float64 `json:"lon"`
+		Country string  `json:"country"`
+	}
+	if country != "" {
+		zip += "," + country
+	}
+	params := make(url.Values)
+	params.Set("zip", zip)
+	if err := c.makeRequest(ctx, &resp, "geo/1.0/zip", params); err != nil {
+		return Location{}, err
+	}
+	return Location{
+		Coords:  Coords{Lat: resp.Lat, Lon: resp.Lon},
+		Zip:     resp.Zip,
+		Name:    resp.Name,
+		Country: resp.Country,
+	}, nil
+}
+
+// DaySummary aggregates the weather at a location over a single day. Values
+// described as afternoon are taken at 12:00 local time.
+type DaySummary struct {
+	Date time.Time
+
+	TemperatureMin       float64
+	TemperatureMax       float64
+	TemperatureMorning   float64
+	TemperatureAfternoon float64
+	TemperatureEvening   float64
+	TemperatureNight     float64
+
+	// Humidity, Pressure and Clouds are afternoon values.
+	Humidity float64
+	Pressure float64
+	Clouds   float64
+
+	// Precipitation is the total precipitation over the day in mm.
+	Precipitation float64
+
+	WindSpeedMax     float64
+	WindDirectionMax float64
+	Units            Units
+	Lat              float64
+	Lon              float64
+}
+
+// GetDaySummary returns the summary of the weather at lat and lon on the
+// calendar day of date in its location, using the One Call 3.0 API.
+func (c Client) GetDaySummary(ctx context.Context, lat, lon float64, date time.Time) (DaySummary, error) {
+	if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return DaySummary{}, fmt.Errorf("weather: invalid coordinates %g,%g", lat, lon)
+	}
+	if date.IsZero() {
+		return DaySummary{}, errors.New("weather: no date given")
+	}
+
+	var resp struct {
+		Lat        float64 `json:"lat"`
+		Lon        float64 `json:"lon"`
+		Date       string  `json:"date"`
+		CloudCover struct {
+			Afternoon float64 `json:"afternoon"`
+		} `json:"cloud_cover"`
+		Humidity struct {
+			Afternoon float64 `json:"afternoon"`
+		} `json:"humidity"`
+		Precipitation struct {
+			Total float64 `json:"total"`
+		} `json:"precipitation"`
+		Temperature struct {
+			Min       float64 `json:"min"`
+			Max       float64 `json:"max"`
+			Morning   float64 `json:"morning"`
+			Afternoon float64 `json:"afternoon"`
+			Evening   float64 `json:"evening"`
+			Night     float64 `json:"night"`
+		} `json:"temperature"`
+		Pressure struct {
+			Afternoon float64 `json:"afternoon"`
+		} `json:"pressure"`
+		Wind struct {
+			Max struct {
+				Speed     float64 `json:"speed"`
+				Direction float64 `json:"direction"`
+			} `json:"max"`
+		} `json:"wind"`
+	}
+	params := make(url.Values)
+	Coords{Lat: lat, Lon: lon}.setParams(params)
+	params.Set("date", date.Format("2006-01-02"))
+	if err := c.makeRequest(ctx, &resp, c.dataPath("3.0", "onecall/day_summary"), params); err != nil {
+		return DaySummary{}, err
+	}
+
+	day, err := time.ParseInLocation("2006-01-02", resp.Date, date.Location())
+	if err != nil {
+		return DaySummary{}, fmt.Errorf("weather: invalid date in day summary: %w", err)
+	}
+	return DaySummary{
+		Date:                 day,
+		TemperatureMin:       resp.Temperature.Min,
+		TemperatureMax:       resp.Temperature.Max,
+		TemperatureMorning:   resp.Temperature.Morning,
+		TemperatureAfternoon: resp.Temperature.Afternoon,
+		TemperatureEvening:   resp.Temperature.Evening,
+		TemperatureNight:     resp.Temperature.Night,
+		Humidity:             c.humidity(resp.Humidity.Afternoon),
+		Pressure:             resp.Pressure.Afternoon,
+		Clouds:               resp.CloudCover.Afternoon,
+		Precipitation:        resp.Precipitation.Total,
+		WindSpeedMax:         resp.Wind.Max.Speed,
+		WindDirectionMax:     resp.Wind.Max.Direction,
+		Units:                c.units,
+		Lat:                  resp.Lat,
+		Lon:                  resp.Lon,
+	}, nil
+}
+
+// ValidateAPIKey makes a minimal request to check whether the API accepts the
+// client's API key. It returns false with a nil error if the key is missing,
+// invalid or not yet activated, and an error if the check itself failed.
+func (c Client) ValidateAPIKey(ctx context.Context) (bool, error) {
+	_, err := c.GetCurrentWeatherAt(ctx, Coords{Lat: 0, Lon: 0})
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, ErrNoAPIKey), errors.Is(err, ErrInvalidAPIKey), errors.Is(err, ErrInactiveAPIKey):
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// GetWeatherBundle fetches the current weather and the forecast for zip
+// concurrently. If either request fails, the other is cancelled and the first
+// error is returned.
+func (c Client) GetWeatherBundle(ctx context.Context, zip string) (Weather, Forecast, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+		current  Weather
+		forecast Forecast
+	)
+	fail := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		w, err := c.GetCurrentWeather(ctx, zip)
+		if err != nil {
+			fail(err)
+			return
+		}
+		current = w
+	}()
+	go func() {
+		defer wg.Done()
+		f, err := c.GetForecast(ctx, zip)
+		if err != nil {
+			fail(err)
+			return
+		}
+		forecast = f
+	}()
+	wg.Wait()
+
+	if firstErr != nil {
+		return Weather{}, nil, firstErr
+	}
+	return current, forecast, nil
+}
+
+// GetCurrentWeatherAllUnits fetches the current weather at zip once and
+// converts it to each of the supported units.
+func (c Client) GetCurrentWeatherAllUnits(ctx context.Context, zip string) (map[Units]Weather, error) {
+	w, err := c.GetCurrentWeather(ctx, zip)
+	if err != nil {
+		return nil, err
+	}
+	return map[Units]Weather{
+		Kelvin:   w.ConvertTo(Kelvin),
+		Imperial: w.ConvertTo(Imperial),
+		Metric:   w.ConvertTo(Metric),
+	}, nil
+}
+
+// ForecastAccuracy returns the absolute difference between the predicted
+// temperature and the current temperature at zip, in the client's units.
+func (c Client) ForecastAccuracy(ctx context.Context, zip string, predicted Weather) (float64, error) {
+	actual, err := c.GetCurrentWeather(ctx, zip)
+	if err != nil {
+		return 0, err
+	}
+	predicted = predicted.ConvertTo(actual.Units)
+	return math.Abs(actual.Temperature - predicted.Temperature), nil
+}
+
+// GetCurrentWeatherBatchFailFast fetches the current weather for each zip code
+// concurrently. The results are returned in the same order as zips. If any
+// request fails, the outstanding requests are cancelled and the first error is
+// returned.
+func (c Client) GetCurrentWeatherBatchFailFast(ctx context.Context, zips []string) ([]Weather, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	weathers := make([]Weather, len(zips))
+	for i, zip := range zips {
+		wg.Add(1)
+		go func(i int, zip string) {
+			defer wg.Done()
+			w, err := c.GetCurrentWeather(ctx, zip)
+			if err != nil {
+				errOnce.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			weathers[i] = w
+		}(i, zip)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return weathers, nil
+}
+
+type Forecast []Weather
+
+// byDay groups the entries by calendar day in the location of the first
+// entry, returning the sorted day keys (formatted as 20060102) alongside the
+// groups. The entries themselves are left in their own locations.
+func (f Forecast) byDay() ([]string, map[string]Forecast) {
+	days := make(map[string]Forecast)
+	keys := make([]string, 0)
+	if len(f) == 0 {
+		return keys, days
+	}
+	loc := f[0].Date.Location()
+	for _, w := range f {
+		key := w.Date.In(loc).Format("20060102")
+		if _, seen := days[key]; !seen {
+			keys = append(keys, key)
+		}
+		days[key] = append(days[key], w)
+	}
+	sort.Strings(keys)
+	return keys, days
+}
+
+// DailyAggregator specifies how DailyWith reduces a day's entries to each
+// field of the daily entry. Nil functions use the corresponding function from
+// DefaultDailyAggregator().
+type DailyAggregator struct {
+	Temperature              func(Forecast) float64
+	TemperatureMin           func(Forecast) float64
+	TemperatureMax           func(Forecast) float64
+	FeelsLike                func(Forecast) float64
+	Humidity                 func(Forecast) float64
+	Pressure                 func(Forecast) float64
+	Clouds                   func(Forecast) float64
+	WindSpeed                func(Forecast) float64
+	PrecipitationProbability func(Forecast) float64
+}
+
+var defaultDailyAggregator = DailyAggregator{
+	Temperature:              Forecast.AverageTemperature,
+	TemperatureMin:           Forecast.MinimumTemperature,
+	TemperatureMax:           Forecast.MaximumTemperature,
+	FeelsLike:                Forecast.AverageFeelsLike,
+	Humidity:                 Forecast.AverageHumidity,
+	Pressure:                 Forecast.AveragePressure,
+	Clouds:                   Forecast.AverageClouds,
+	WindSpeed:                Forecast.AverageWindSpeed,
+	PrecipitationProbability: Forecast.MaximumPrecipitationProbability,
+}
+
+// DefaultDailyAggregator returns the aggregator used by Daily, which averages
+// most fields but takes the lowest TemperatureMin, the highest TemperatureMax
+// and the highest PrecipitationProbability. Changing the returned value
+// doesn't affect Daily.
+func DefaultDailyAggregator() DailyAggregator {
+	return defaultDailyAggregator
+}
+
+func (agg DailyAggregator) withDefaults() DailyAggregator {
+	def := defaultDailyAggregator
+	for _, fn := range []struct{ dst, src *func(Forecast) float64 }{
+		{&agg.Temperature, &def.Temperature},
+		{&agg.TemperatureMin, &def.TemperatureMin},
+		{&agg.TemperatureMax, &def.TemperatureMax},
+		{&agg.FeelsLike, &def.FeelsLike},
+		{&agg.Humidity, &def.Humidity},
+		{&agg.Pressure, &def.Pressure},
+		{&agg.Clouds, &def.Clouds},
+		{&agg.WindSpeed, &def.WindSpeed},
+		{&agg.PrecipitationProbability, &def.PrecipitationProbability},
+	} {
+		if *fn.dst == nil {
+			*fn.dst = *fn.src
+		}
+	}
+	return agg
+}
+
+// aggregate reduces entries, which must not be empty, to a single entry for
+// date.
+func (agg DailyAggregator) aggregate(date time.Time, entries Forecast) Weather {
+	return Weather{
+		Date:                     date,
+		Humidity:                 agg.Humidity(entries),
+		Pressure:                 agg.Pressure(entries),
+		Temperature:              agg.Temperature(entries),
+		TemperatureMin:           agg.TemperatureMin(entries),
+		TemperatureMax:           agg.TemperatureMax(entries),
+		FeelsLike:                agg.FeelsLike(entries),
+		Clouds:                   agg.Clouds(entries),
+		PrecipitationProbability: agg.PrecipitationProbability(entries),
+		Sunrise:                  entries[0].Sunrise,
+		Sunset:                   entries[0].Sunset,
+		WindSpeed:                agg.WindSpeed(entries),
+		Units:                    entries[0].Units,
+		Lat:                      entries[0].Lat,
+		Lon:                      entries[0].Lon,
+	}
+}
+
+// Daily aggregates the entries by calendar day in the location of the first
+// entry. Use DailyIn to choose the location explicitly.
+func (f Forecast) Daily() Forecast {
+	return f.DailyWith(defaultDailyAggregator)
+}
+
+// DailyIn is like Daily, but groups the entries by calendar day in loc. When
+// the entries are already in loc, it returns the same result as Daily.
+func (f Forecast) DailyIn(loc *time.Location) Forecast {
+	return f.Map(func(w Weather) Weather {
+		w.Date = w.Date.In(loc)
+		return w
+	}).Daily()
+}
+
+// DailyWith is like Daily, but reduces each day's entries using agg.
+func (f Forecast) DailyWith(agg DailyAggregator) Forecast {
+	agg = agg.withDefaults()
+
+	if len(f) == 0 {
+		return Forecast{}
+	}
+	loc := f[0].Date.Location()
+	keys, days := f.byDay()
+
+	dailyForecast := make(Forecast, 0, len(days))
+	for _, key := range keys {
+		date, _ := time.ParseInLocation("20060102", key, loc)
+		dailyForecast = append(dailyForecast, agg.aggregate(date, days[key]))
+	}
+
+	return dailyForecast
+}
+
+// Bucketize aggregates the entries into consecutive buckets of the given
+// interval, aligned to midnight in loc, using the same aggregates as Daily.
+// Each resulting entry is dated at the start of its bucket. It returns nil if
+// interval is not positive.
+func (f Forecast) Bucketize(interval time.Duration, loc *time.Location) Forecast {
+	if interval <= 0 {
+		return nil
+	}
+
+	buckets := make(map[time.Time]Forecast)
+	var starts []time.Time
+	for _, w := range f {
+		t := w.Date.In(loc)
+		midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		start := midnight.Add(t.Sub(midnight) / interval * interval)
+		if _, seen := buckets[start]; !seen {
+			starts = append(starts, start)
+		}
+		buckets[start] = append(buckets[start], w)
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+
+	agg := defaultDailyAggregator
+	bucketed := make(Forecast, 0, len(starts))
+	for _, start := range starts {
+		bucketed = append(bucketed, agg.aggregate(start, buckets[start]))
+	}
+	return bucketed
+}
+
+// DailyIntegral is like Daily, but computes the daily means of temperature,
+// feels-like temperature, humidity, pressure, cloud cover and wind speed by
+// trapezoidal integration over time rather than as a plain average of the
+// samples. This weights each sample by the time it spans, which matters when
+// samples are unevenly spaced or a day is only partly covered.
+func (f Forecast) DailyIntegral() Forecast {
+	return f.DailyWith(DailyAggregator{
+		Temperature: timeWeightedMean(func(w Weather) float64 { return w.Temperature }),
+		FeelsLike:   timeWeightedMean(func(w Weather) float64 { return w.FeelsLike }),
+		Humidity:    timeWeightedMean(func(w Weather) float64 { return w.Humidity }),
+		Pressure:    timeWeightedMean(func(w Weather) float64 { return w.Pressure }),
+		Clouds:      timeWeightedMean(func(w Weather) float64 { return w.Clouds }),
+		WindSpeed:   timeWeightedMean(func(w Weather) float64 { return w.WindSpeed }),
+	})
+}
+
+// timeWeightedMean returns a function computing the trapezoidal time-weighted
+// mean of value over a forecast. Forecasts spanning no time fall back to the
+// plain mean.
+func timeWeightedMean(value func(Weather) float64) func(Forecast) float64 {
+	return func(f Forecast) float64 {
+		sorted := make(Forecast, len(f))
+		copy(sorted, f)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i].Date.Before(sorted[j].Date) })
+
+		var area, span, sum float64
+		for i, w := range sorted {
+			sum += value(w)
+			if i == 0 {
+				continue
+			}
+			dt := w.Date.Sub(sorted[i-1].Date).Seconds()
+			area += (value(sorted[i-1]) + value(w)) / 2 * dt
+			span += dt
+		}
+		if span == 0 {
+			return sum / float64(len(sorted))
+		}
+		return area / span
+	}
+}
+
+// Tomorrow returns the daily entry for the calendar day after now, in the
+// location of the forecast. ok is false if the forecast doesn't cover that day.
+func (f Forecast) Tomorrow(now time.Time) (day Weather, ok bool) {
+	if len(f) == 0 {
+		return Weather{}, false
+	}
+	key := now.In(f[0].Date.Location()).AddDate(0, 0, 1).Format("20060102")
+	for _, d := range f.Daily() {
+		if d.Date.Format("20060102") == key {
+			return d, true
+		}
+	}
+	return Weather{}, false
+}
+
+// EstimatedSunHours is a rough estimate of the hours of sunshine over the
+// forecast period. Each day's daylight hours, taken from its sunrise and
+// sunset, are weighted by the fraction of the sky that is clear. Days without
+// sunrise and sunset data are not counted.
+func (f Forecast) EstimatedSunHours() float64 {
+	total := 0.0
+	for _, day := range f.Daily() {
+		if day.Sunrise.IsZero() || day.Sunset.IsZero() {
+			continue
+		}
+		daylight := day.Sunset.Sub(day.Sunrise).Hours()
+		total += daylight * (1 - day.Clouds/100)
+	}
+	return total
+}
 
-type Weather struct {
-	Date           time.Time
-	Temperature    float64
-	TemperatureMin float64
-	TemperatureMax float64
-	Humidity       float64
+// LongestDryStreak returns the longest run of consecutive entries without
+// precipitation, as a duration based on the forecast's Resolution. An entry
+// is wet if it has a Rain volume or a precipitating condition, as reported by
+// PrecipitationType, and dry otherwise.
+func (f Forecast) LongestDryStreak() time.Duration {
+	return f.longestStreak(false)
 }
 
-type Units string
+// LongestWetStreak returns the longest run of consecutive entries with
+// precipitation, as a duration based on the forecast's Resolution. Entries
+// are wet or dry as for LongestDryStreak.
+func (f Forecast) LongestWetStreak() time.Duration {
+	return f.longestStreak(true)
+}
+
+func (f Forecast) longestStreak(wet bool) time.Duration {
+	longest, current := 0, 0
+	for _, w := range f {
+		if (w.Rain > 0 || w.PrecipitationType() != "none") != wet {
+			current = 0
+			continue
+		}
+		current++
+		if current > longest {
+			longest = current
+		}
+	}
+	return time.Duration(longest) * f.Resolution()
+}
+
+// TemperatureHistogram counts the entries whose temperature falls in each bin
+// of width binWidth, keyed by the lower bound of the bin. It returns nil if
+// binWidth is not positive.
+func (f Forecast) TemperatureHistogram(binWidth float64) map[float64]int {
+	if binWidth <= 0 {
+		return nil
+	}
+	bins := make(map[float64]int)
+	for _, w := range f {
+		bins[math.Floor(w.Temperature/binWidth)*binWidth]++
+	}
+	return bins
+}
+
+// NormalizeUnits converts every entry from its own Units to target, so that
+// forecasts fetched in different units can be combined.
+func (f Forecast) NormalizeUnits(target Units) Forecast {
+	return f.Map(func(w Weather) Weather {
+		return w.ConvertTo(target)
+	})
+}
+
+// Coverage returns the earliest and latest dates in the forecast, which need
+// not be sorted. ok is false if the forecast is empty.
+func (f Forecast) Coverage() (start, end time.Time, ok bool) {
+	if len(f) == 0 {
+		return time.Time{}, time.Time{}, false
+	}
+	start, end = f[0].Date, f[0].Date
+	for _, w := range f[1:] {
+		if w.Date.Before(start) {
+			start = w.Date
+		}
+		if w.Date.After(end) {
+			end = w.Date
+		}
+	}
+	return start, end, true
+}
+
+// conditionSeverity orders condition names from least (Clear) to most
+// (Tornado) severe. Unknown conditions rank with Clear.
+var conditionSeverity = map[string]int{
+	"Clear":        0,
+	"Clouds":       1,
+	"Mist":         2,
+	"Haze":         2,
+	"Fog":          2,
+	"Smoke":        3,
+	"Dust":         3,
+	"Sand":         3,
+	"Ash":          3,
+	"Drizzle":      4,
+	"Rain":         5,
+	"Snow":         6,
+	"Squall":       7,
+	"Thunderstorm": 8,
+	"Tornado":      9,
+}
+
+// MostCommonCondition returns the condition reported by the most entries.
+// Ties go to the more severe condition, e.g. Rain over Clouds over Clear. It
+// returns "" for an empty forecast.
+func (f Forecast) MostCommonCondition() string {
+	best, bestCount := "", 0
+	for cond, count := range f.CountByCondition() {
+		switch {
+		case count > bestCount,
+			count == bestCount && conditionSeverity[cond] > conditionSeverity[best],
+			count == bestCount && conditionSeverity[cond] == conditionSeverity[best] && cond < best:
+			best, bestCount = cond, count
+		}
+	}
+	return best
+}
+
+func (f Forecast) CountByCondition() map[string]int {
+	counts := make(map[string]int)
+	for _, w := range f {
+		counts[w.Condition]++
+	}
+	return counts
+}
+
+func (f Forecast) Filter(pred func(Weather) bool) Forecast {
+	filtered := make(Forecast, 0, len(f))
+	for _, w := range f {
+		if pred(w) {
+			filtered = append(filtered, w)
+		}
+	}
+	return filtered
+}
+
+func (f Forecast) Map(fn func(Weather) Weather) Forecast {
+	mapped := make(Forecast, 0, len(f))
+	for _, w := range f {
+		mapped = append(mapped, fn(w))
+	}
+	return mapped
+}
 
 const (
-	Kelvin   Units = "kelvin"
-	Imperial Units = "imperial"
-	Metric   Units = "metric"
+	pressureTrendWindow = 6 * time.Hour
+
+	// pressureTrendThreshold is the rate of change, in hPa/hour, below
+	// which pressure is considered steady.
+	pressureTrendThreshold = 1.0 / 3
 )
 
-type Option func(c *Client)
+// PressureTrend returns "rising", "falling" or "steady" based on the
+// least-squares slope of the pressure over the first six hours of the
+// forecast.
+func (f Forecast) PressureTrend() string {
+	if len(f) < 2 {
+		return "steady"
+	}
 
-func WithAPIKey(k string) Option {
-	return func(c *Client) {
-		c.apiKey = k
+	start := f[0].Date
+	var n, sumX, sumY, sumXY, sumXX float64
+	for _, w := range f {
+		x := w.Date.Sub(start).Hours()
+		if x > pressureTrendWindow.Hours() {
+			break
+		}
+		n++
+		sumX += x
+		sumY += w.Pressure
+		sumXY += x * w.Pressure
+		sumXX += x * x
+	}
+
+	denom := n*sumXX - sumX*sumX
+	if n < 2 || denom == 0 {
+		return "steady"
+	}
+	slope := (n*sumXY - sumX*sumY) / denom
+	switch {
+	case slope > pressureTrendThreshold:
+		return "rising"
+	case slope < -pressureTrendThreshold:
+		return "falling"
+	default:
+		return "steady"
 	}
 }
 
-func WithUnits(units Units) Option {
-	return func(c *Client) {
-		c.units = units
+// GroupByWeek groups the entries by ISO week, keyed like "2006-W01". Weeks
+// are determined in the location of the first entry.
+func (f Forecast) GroupByWeek() map[string]Forecast {
+	weeks := make(map[string]Forecast)
+	if len(f) == 0 {
+		return weeks
+	}
+	loc := f[0].Date.Location()
+	for _, w := range f {
+		year, week := w.Date.In(loc).ISOWeek()
+		key := fmt.Sprintf("%04d-W%02d", year, week)
+		weeks[key] = append(weeks[key], w)
 	}
+	return weeks
 }
 
-type Client struct {
-	apiKey     string
-	units      Units
-	httpClient *http.Client
+// DailyTempRanges maps each day (formatted as 20060102) to the difference
+// between its maximum and minimum temperatures. Days are grouped as by Daily.
+func (f Forecast) DailyTempRanges() map[string]float64 {
+	keys, days := f.byDay()
+	ranges := make(map[string]float64, len(keys))
+	for _, key := range keys {
+		day := days[key]
+		ranges[key] = day.MaximumTemperature() - day.MinimumTemperature()
+	}
+	return ranges
 }
 
-func NewClient(opts ...Option) Client {
-	c := Client{
-		units:      Kelvin,
-		httpClient: &http.Client{Timeout: 5 * time.Second},
+// AverageDiurnalRange returns the mean across days of the difference between
+// each day's maximum and minimum temperatures, as aggregated by Daily. It
+// returns 0 for an empty forecast.
+func (f Forecast) AverageDiurnalRange() float64 {
+	daily := f.Daily()
+	if len(daily) == 0 {
+		return 0
 	}
-	for _, opt := range opts {
-		opt(&c)
+	total := 0.0
+	for _, day := range daily {
+		total += day.TemperatureMax - day.TemperatureMin
 	}
-	return c
+	return total / float64(len(daily))
 }
 
-func (c Client) makeRequest(ctx context.Context, dest interface{}, endpoint string, queryParams url.Values) error {
-	req, err := http.NewRequest("GET", OpenWeatherMapURL+endpoint, nil)
-	if err != nil {
-		return err
+func (f Forecast) Reverse() Forecast {
+	reversed := make(Forecast, len(f))
+	for i, w := range f {
+		reversed[len(f)-1-i] = w
 	}
+	return reversed
+}
 
-	queryParams.Set("APPID", c.apiKey)
-	if c.units != Kelvin {
-		queryParams.Set("units", string(c.units))
+// WeekendOnly returns the entries that fall on a Saturday or Sunday in the
+// location of the first entry, like Daily, so that mixed locations agree on
+// which days are the weekend.
+func (f Forecast) WeekendOnly() Forecast {
+	if len(f) == 0 {
+		return Forecast{}
 	}
-	req.URL.RawQuery = queryParams.Encode()
+	loc := f[0].Date.Location()
+	return f.Filter(func(w Weather) bool {
+		day := w.Date.In(loc).Weekday()
+		return day == time.Saturday || day == time.Sunday
+	})
+}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return err
+// InterpolateMissing returns a copy of the forecast with missing humidity and
+// pressure values filled in by linear interpolation over time. A value is
+// considered missing if it is zero and there are non-zero values both before
+// and after it; leading and trailing zeros are left as they are.
+func (f Forecast) InterpolateMissing() Forecast {
+	filled := make(Forecast, len(f))
+	copy(filled, f)
+	interpolateZeros(filled, func(w *Weather) *float64 { return &w.Humidity })
+	interpolateZeros(filled, func(w *Weather) *float64 { return &w.Pressure })
+	return filled
+}
+
+func interpolateZeros(f Forecast, field func(w *Weather) *float64) {
+	prev := -1
+	for i := range f {
+		if *field(&f[i]) == 0 {
+			continue
+		}
+		if prev >= 0 && i-prev > 1 {
+			from, to := *field(&f[prev]), *field(&f[i])
+			span := f[i].Date.Sub(f[prev].Date).Seconds()
+			for j := prev + 1; j < i; j++ {
+				frac := float64(j-prev) / float64(i-prev)
+				if span > 0 {
+					frac = f[j].Date.Sub(f[prev].Date).Seconds() / span
+				}
+				*field(&f[j]) = from + (to-from)*frac
+			}
+		}
+		prev = i
 	}
+}
 
-	if resp.StatusCode != http.StatusOK {
-		b, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return err
+// SunTimesByDay maps each day (formatted as 20060102) to its sunrise and
+// sunset, taken from the first entry of the day that has them. Days are
+// grouped as by Daily, and days without sunrise and sunset data are omitted.
+func (f Forecast) SunTimesByDay() map[string][2]time.Time {
+	keys, days := f.byDay()
+	times := make(map[string][2]time.Time, len(keys))
+	for _, key := range keys {
+		for _, w := range days[key] {
+			if !w.Sunrise.IsZero() && !w.Sunset.IsZero() {
+				times[key] = [2]time.Time{w.Sunrise, w.Sunset}
+				break
+			}
 		}
-		return errors.New(string(b))
 	}
+	return times
+}
 
-	return json.NewDecoder(resp.Body).Decode(dest)
+// PeakWindPerDay returns, for each calendar day in order, the entry with the
+// highest wind speed. Days are grouped as by Daily, and ties go to the
+// earliest entry.
+func (f Forecast) PeakWindPerDay() []Weather {
+	keys, days := f.byDay()
+	peaks := make([]Weather, 0, len(keys))
+	for _, key := range keys {
+		peak := days[key][0]
+		for _, w := range days[key][1:] {
+			if w.WindSpeed > peak.WindSpeed {
+				peak = w
+			}
+		}
+		peaks = append(peaks, peak)
+	}
+	return peaks
 }
 
-func (c Client) GetForecast(ctx context.Context, zip string) (Forecast, error) {
-	var resp struct {
-		List []struct {
-			Timestamp int64 `json:"dt"`
-			Main      struct {
-				Temperature    float64 `json:"temp"`
-				TemperatureMin float64 `json:"temp_min"`
-				TemperatureMax float64 `json:"temp_max"`
-				Humidity       float64 `json:"humidity"`
-			} `json:"main"`
-		} `json:"list"`
+// ApparentHottestDay returns the daily entry for the day with the highest
+// feels-like temperature at any point, which can differ from the day with the
+// highest actual temperature. ok is false if the forecast is empty.
+func (f Forecast) ApparentHottestDay() (day Weather, ok bool) {
+	keys, days := f.byDay()
+	hottest, max := "", math.Inf(-1)
+	for _, key := range keys {
+		for _, w := range days[key] {
+			if w.FeelsLike > max {
+				hottest, max = key, w.FeelsLike
+			}
+		}
+	}
+	if hottest == "" {
+		return Weather{}, false
 	}
+	for _, d := range f.Daily() {
+		if d.Date.Format("20060102") == hottest {
+			return d, true
+		}
+	}
+	return Weather{}, false
+}
 
-	params := make(url.Values)
-	params.Set("zip", zip)
-	if err := c.makeRequest(ctx, &resp, "forecast", params); err != nil {
-		return nil, err
+// ClearestDay returns the daily entry with the lowest average cloud cover, as
+// aggregated by Daily. Ties go to the earliest day. ok is false if the
+// forecast is empty.
+func (f Forecast) ClearestDay() (day Weather, ok bool) {
+	for _, d := range f.Daily() {
+		if !ok || d.Clouds < day.Clouds {
+			day, ok = d, true
+		}
 	}
+	return day, ok
+}
 
-	weathers := make(Forecast, 0, len(resp.List))
-	for _, w := range resp.List {
-		weathers = append(weathers, Weather{
-			Date:           time.Unix(w.Timestamp, 0),
-			Humidity:       w.Main.Humidity,
-			Temperature:    w.Main.Temperature,
-			TemperatureMin: w.Main.TemperatureMin,
-			TemperatureMax: w.Main.TemperatureMax,
-		})
+// OutdoorWeights specifies how BestOutdoorDayWith ranks days. Each day is
+// penalized by the sum of the weighted distance of its high temperature from
+// IdealTemperature in °C, its precipitation probability in percent, its wind
+// speed in meters/sec and its cloud cover in percent. Temperatures are
+// converted from the day's units as by ConvertTo, so they are assumed to be in
+// °C if its units are unset.
+type OutdoorWeights struct {
+	IdealTemperature float64
+
+	Temperature              float64
+	PrecipitationProbability float64
+	WindSpeed                float64
+	Clouds                   float64
+}
+
+var DefaultOutdoorWeights = OutdoorWeights{
+	IdealTemperature:         21,
+	Temperature:              1,
+	PrecipitationProbability: 0.5,
+	WindSpeed:                1,
+	Clouds:                   0.1,
+}
+
+// BestOutdoorDay returns the daily entry best suited to spending time
+// outdoors, ranked using DefaultOutdoorWeights. ok is false if the forecast is
+// empty.
+func (f Forecast) BestOutdoorDay() (day Weather, ok bool) {
+	return f.BestOutdoorDayWith(DefaultOutdoorWeights)
+}
+
+// BestOutdoorDayWith is like BestOutdoorDay, but ranks the days using
+// weights. Ties go to the earliest day.
+func (f Forecast) BestOutdoorDayWith(weights OutdoorWeights) (day Weather, ok bool) {
+	best := math.Inf(1)
+	for _, d := range f.Daily() {
+		penalty := weights.Temperature*math.Abs(d.ConvertTo(Metric).TemperatureMax-weights.IdealTemperature) +
+			weights.PrecipitationProbability*d.PrecipitationProbability*100 +
+			weights.WindSpeed*d.windSpeedMetersPerSec() +
+			weights.Clouds*d.Clouds
+		if penalty < best {
+			day, ok, best = d, true, penalty
+		}
 	}
+	return day, ok
+}
 
-	return weathers, nil
+// ClampTemperatures returns a copy of the forecast with every temperature
+// field limited to the range [min, max]. The bounds must be in the same units
+// as the forecast.
+func (f Forecast) ClampTemperatures(min, max float64) Forecast {
+	clamp := func(t float64) float64 {
+		return math.Max(min, math.Min(max, t))
+	}
+	return f.Map(func(w Weather) Weather {
+		w.Temperature = clamp(w.Temperature)
+		w.TemperatureMin = clamp(w.TemperatureMin)
+		w.TemperatureMax = clamp(w.TemperatureMax)
+		w.FeelsLike = clamp(w.FeelsLike)
+		return w
+	})
 }
 
-func (c Client) GetCurrentWeather(ctx context.Context, zip string) (Weather, error) {
-	var resp struct {
-		Timestamp int64 `json:"dt"`
-		Main      struct {
-			Temperature    float64 `json:"temp"`
-			TemperatureMin float64 `json:"temp_min"`
-			TemperatureMax float64 `json:"temp_max"`
-			Humidity       float64 `json:"humidity"`
-		} `json:"main"`
+// SolarEnergyEstimate is a rough estimate of the energy, in kWh, produced over
+// the forecast period by solar panels rated at panelKW. It assumes the panels
+// produce their rated output under a clear midday sun, that irradiance follows
+// a half sine wave between sunrise and sunset (averaging 2/π of its peak), and
+// that cloud cover reduces output in proportion, as in EstimatedSunHours. It
+// ignores panel orientation, temperature and system losses.
+func (f Forecast) SolarEnergyEstimate(panelKW float64) float64 {
+	return panelKW * f.EstimatedSunHours() * 2 / math.Pi
+}
+
+// SmoothHumidity returns a copy of the forecast with humidity replaced by its
+// moving average over window entries centered on each entry. Near the ends of
+// the forecast, the window is truncated.
+func (f Forecast) SmoothHumidity(window int) Forecast {
+	humidity := make([]float64, len(f))
+	for i, w := range f {
+		humidity[i] = w.Humidity
 	}
+	smoothed := movingAverage(humidity, window)
 
-	params := make(url.Values)
-	params.Set("zip", zip)
-	if err := c.makeRequest(ctx, &resp, "weather", params); err != nil {
-		return Weather{}, err
+	result := make(Forecast, len(f))
+	for i, w := range f {
+		w.Humidity = smoothed[i]
+		result[i] = w
 	}
+	return result
+}
 
-	return Weather{
-		Date:           time.Unix(resp.Timestamp, 0),
-		Temperature:    resp.Main.Temperature,
-		TemperatureMax: resp.Main.TemperatureMax,
-		TemperatureMin: resp.Main.TemperatureMin,
-	}, nil
+// movingAverage returns the centered moving average of values over window
+// elements, truncating the window at either end. A window of less than 2
+// returns a copy of values.
+func movingAverage(values []float64, window int) []float64 {
+	avg := make([]float64, len(values))
+	if window < 2 {
+		copy(avg, values)
+		return avg
+	}
+
+	before := (window - 1) / 2
+	after := window - 1 - before
+	for i := range values {
+		lo, hi := i-before, i+after
+		if lo < 0 {
+			lo = 0
+		}
+		if hi >= len(values) {
+			hi = len(values) - 1
+		}
+		sum := 0.0
+		for _, v := range values[lo : hi+1] {
+			sum += v
+		}
+		avg[i] = sum / float64(hi-lo+1)
+	}
+	return avg
 }
 
-type Forecast []Weather
+var timeSeriesFields = map[string]func(Weather) float64{
+	"temperature":               func(w Weather) float64 { return w.Temperature },
+	"temperature_min":           func(w Weather) float64 { return w.TemperatureMin },
+	"temperature_max":           func(w Weather) float64 { return w.TemperatureMax },
+	"feels_like":                func(w Weather) float64 { return w.FeelsLike },
+	"humidity":                  func(w Weather) float64 { return w.Humidity },
+	"pressure":                  func(w Weather) float64 { return w.Pressure },
+	"sea_level_pressure":        func(w Weather) float64 { return w.SeaLevelPressure },
+	"ground_level_pressure":     func(w Weather) float64 { return w.GroundLevelPressure },
+	"precipitation_probability": func(w Weather) float64 { return w.PrecipitationProbability },
+	"condition_id":              func(w Weather) float64 { return float64(w.ConditionID) },
+	"clouds":                    func(w Weather) float64 { return w.Clouds },
+	"rain":                      func(w Weather) float64 { return w.Rain },
+	"wind_speed":                func(w Weather) float64 { return w.WindSpeed },
+	"wind_direction":            func(w Weather) float64 { return w.WindDirection },
+	"lat":                       func(w Weather) float64 { return w.Lat },
+	"lon":                       func(w Weather) float64 { return w.Lon },
+}
 
-func (f Forecast) Daily() Forecast {
-	days := make(map[string]Forecast)
-	keys := make([]string, 0)
-	var loc *time.Location
+// ToTimeSeries returns the dates of the entries alongside the values of the
+// named field, which is one of the keys of Weather.Snapshot with a numeric
+// value.
+func (f Forecast) ToTimeSeries(field string) ([]time.Time, []float64, error) {
+	value, ok := timeSeriesFields[field]
+	if !ok {
+		return nil, nil, fmt.Errorf("weather: unknown time series field %q", field)
+	}
+
+	times := make([]time.Time, 0, len(f))
+	values := make([]float64, 0, len(f))
+	for _, w := range f {
+		times = append(times, w.Date)
+		values = append(values, value(w))
+	}
+	return times, values, nil
+}
+
+// ComfortableHours returns the time covered by entries with a temperature
+// between tempMin and tempMax and a humidity of at most maxHumidity, based on
+// the forecast's Resolution.
+func (f Forecast) ComfortableHours(tempMin, tempMax, maxHumidity float64) time.Duration {
+	comfortable := f.Filter(func(w Weather) bool {
+		return w.Temperature >= tempMin && w.Temperature <= tempMax && w.Humidity <= maxHumidity
+	})
+	return time.Duration(len(comfortable)) * f.Resolution()
+}
+
+// Thresholds used by DetectFrontPassage.
+var (
+	// FrontPressureDrop is the minimum fall in pressure, in hPa per three
+	// hours, between consecutive entries.
+	FrontPressureDrop = 3.0
+
+	// FrontWindShift is the minimum change in wind direction, in degrees,
+	// between consecutive entries.
+	FrontWindShift = 45.0
+)
+
+// DetectFrontPassage returns the dates of entries where, compared with the
+// previous entry, the pressure fell by at least FrontPressureDrop and the wind
+// direction changed by at least FrontWindShift, which together suggest that a
+// weather front has passed.
+func (f Forecast) DetectFrontPassage() []time.Time {
+	var passages []time.Time
+	for i := 1; i < len(f); i++ {
+		prev, cur := f[i-1], f[i]
+		gap := cur.Date.Sub(prev.Date)
+		if gap <= 0 {
+			continue
+		}
+		drop := (prev.Pressure - cur.Pressure) * (3 * time.Hour).Hours() / gap.Hours()
+		shift := math.Abs(math.Mod(cur.WindDirection-prev.WindDirection, 360))
+		if shift > 180 {
+			shift = 360 - shift
+		}
+		if drop >= FrontPressureDrop && shift >= FrontWindShift {
+			passages = append(passages, cur.Date)
+		}
+	}
+	return passages
+}
+
+type Extremes struct {
+	MinTemp     float64
+	MinTempTime time.Time
+	MaxTemp     float64
+	MaxTempTime time.Time
+
+	MaxWind     float64
+	MaxWindTime time.Time
+
+	MinHumidity float64
+	MaxHumidity float64
+
+	MaxPrecipitationProbability float64
+}
+
+// Extremes computes the headline extremes of the forecast in a single pass.
+// MinTemp and MaxTemp match MinimumTemperature and MaximumTemperature, and
+// are likewise infinite for an empty forecast.
+func (f Forecast) Extremes() Extremes {
+	e := Extremes{
+		MinTemp:     math.Inf(1),
+		MaxTemp:     math.Inf(-1),
+		MinHumidity: math.Inf(1),
+		MaxHumidity: math.Inf(-1),
+	}
 	for _, w := range f {
-		if loc == nil {
-			loc = w.Date.Location()
+		if w.TemperatureMin < e.MinTemp {
+			e.MinTemp, e.MinTempTime = w.TemperatureMin, w.Date
 		}
-		key := w.Date.Format("20060102")
-		if _, seen := days[key]; !seen {
-			keys = append(keys, key)
+		if w.TemperatureMax > e.MaxTemp {
+			e.MaxTemp, e.MaxTempTime = w.TemperatureMax, w.Date
 		}
-		days[key] = append(days[key], w)
+		if w.WindSpeed > e.MaxWind {
+			e.MaxWind, e.MaxWindTime = w.WindSpeed, w.Date
+		}
+		e.MinHumidity = math.Min(e.MinHumidity, w.Humidity)
+		e.MaxHumidity = math.Max(e.MaxHumidity, w.Humidity)
+		e.MaxPrecipitationProbability = math.Max(e.MaxPrecipitationProbability, w.PrecipitationProbability)
 	}
-	sort.Strings(keys)
+	return e
+}
 
-	dailyForecast := make(Forecast, 0, len(days))
+// TemperatureVolatility returns the mean absolute change in temperature
+// between consecutive entries, or 0 if there are fewer than two entries.
+func (f Forecast) TemperatureVolatility() float64 {
+	if len(f) < 2 {
+		return 0
+	}
+	total := 0.0
+	for i := 1; i < len(f); i++ {
+		total += math.Abs(f[i].Temperature - f[i-1].Temperature)
+	}
+	return total / float64(len(f)-1)
+}
+
+// TimeToReach returns the first time the temperature crosses target, rising
+// through it if rising is true and falling through it otherwise, assuming the
+// temperature changes linearly between entries. ok is false if it never
+// crosses target in that direction.
+func (f Forecast) TimeToReach(target float64, rising bool) (t time.Time, ok bool) {
+	for i := 1; i < len(f); i++ {
+		prev, next := f[i-1], f[i]
+		crosses := prev.Temperature < target && next.Temperature >= target
+		if !rising {
+			crosses = prev.Temperature > target && next.Temperature <= target
+		}
+		if crosses {
+			frac := (target - prev.Temperature) / (next.Temperature - prev.Temperature)
+			return prev.Date.Add(time.Duration(frac * float64(next.Date.Sub(prev.Date)))), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Thunderstorms returns the entries in the Thunderstorm condition group
+// (condition IDs 2xx).
+func (f Forecast) Thunderstorms() Forecast {
+	return f.Filter(func(w Weather) bool {
+		return w.ConditionGroup() == "Thunderstorm"
+	})
+}
+
+// SevereWindow is a run of consecutive severe forecast entries.
+type SevereWindow struct {
+	Start     time.Time
+	End       time.Time
+	Condition string
+}
+
+// SevereWindows merges consecutive entries for which IsSevere reports true
+// into windows from the first entry's Date to the last's. Each window's
+// Condition is that of its entry with the highest SeverityRank.
+func (f Forecast) SevereWindows() []SevereWindow {
+	var windows []SevereWindow
+	var worst Weather
+	inWindow := false
+	for _, w := range f {
+		if !w.IsSevere() {
+			inWindow = false
+			continue
+		}
+		if !inWindow {
+			windows = append(windows, SevereWindow{Start: w.Date})
+			worst = w
+			inWindow = true
+		}
+		if w.SeverityRank() > worst.SeverityRank() {
+			worst = w
+		}
+		window := &windows[len(windows)-1]
+		window.End = w.Date
+		window.Condition = worst.Condition
+	}
+	return windows
+}
+
+// FeelsColderThan returns the entries whose feels-like temperature is more
+// than threshold below the actual temperature. The threshold is a temperature
+// difference in the forecast's units, so 5 means 5°C (or K) for Metric and
+// Kelvin forecasts, but only about 2.8°C for Imperial ones.
+func (f Forecast) FeelsColderThan(threshold float64) Forecast {
+	return f.Filter(func(w Weather) bool {
+		return w.Temperature-w.FeelsLike > threshold
+	})
+}
+
+// AsOf returns the entries at or after t.
+func (f Forecast) AsOf(t time.Time) Forecast {
+	return f.Filter(func(w Weather) bool {
+		return !w.Date.Before(t)
+	})
+}
+
+// HourlyForDay returns the dates and temperatures of the entries on the same
+// calendar day as day, in the location of the forecast.
+func (f Forecast) HourlyForDay(day time.Time) ([]time.Time, []float64) {
+	if len(f) == 0 {
+		return nil, nil
+	}
+	loc := f[0].Date.Location()
+	key := day.In(loc).Format("20060102")
+
+	var times []time.Time
+	var temps []float64
+	for _, w := range f {
+		if w.Date.In(loc).Format("20060102") == key {
+			times = append(times, w.Date)
+			temps = append(temps, w.Temperature)
+		}
+	}
+	return times, temps
+}
+
+// CumulativeRain returns the dates of the entries alongside the running total
+// of rain volume up to and including each entry.
+func (f Forecast) CumulativeRain() ([]time.Time, []float64) {
+	times := make([]time.Time, 0, len(f))
+	totals := make([]float64, 0, len(f))
+	total := 0.0
+	for _, w := range f {
+		total += w.Rain
+		times = append(times, w.Date)
+		totals = append(totals, total)
+	}
+	return times, totals
+}
+
+// ChanceOfRainByDay maps each day (formatted as 20060102), grouped as by
+// Daily, to the highest precipitation probability of its entries.
+func (f Forecast) ChanceOfRainByDay() map[string]float64 {
+	keys, days := f.byDay()
+	chances := make(map[string]float64, len(keys))
 	for _, key := range keys {
-		hourly := days[key]
-		date, _ := time.ParseInLocation("20060102", key, loc)
-		dailyForecast = append(dailyForecast, Weather{
-			Date:           date,
-			Humidity:       hourly.AverageHumidity(),
-			Temperature:    hourly.AverageTemperature(),
-			TemperatureMin: hourly.MinimumTemperature(),
-			TemperatureMax: hourly.MaximumTemperature(),
-		})
+		chances[key] = days[key].MaximumPrecipitationProbability()
 	}
+	return chances
+}
 
-	return dailyForecast
+// Resolution returns the median gap between consecutive entries, or 0 if there
+// are fewer than two entries.
+func (f Forecast) Resolution() time.Duration {
+	if len(f) < 2 {
+		return 0
+	}
+
+	dates := make([]time.Time, 0, len(f))
+	for _, w := range f {
+		dates = append(dates, w.Date)
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+
+	gaps := make([]time.Duration, 0, len(dates)-1)
+	for i := 1; i < len(dates); i++ {
+		gaps = append(gaps, dates[i].Sub(dates[i-1]))
+	}
+	sort.Slice(gaps, func(i, j int) bool { return gaps[i] < gaps[j] })
+
+	mid := len(gaps) / 2
+	if len(gaps)%2 == 0 {
+		return (gaps[mid-1] + gaps[mid]) / 2
+	}
+	return gaps[mid]
 }
 
 func (f Forecast) MaximumTemperature() float64 {
@@ -216,3 +2847,45 @@ func (f Forecast) AverageHumidity() float64 {
 	}
 	return hum / float64(len(f))
 }
+
+func (f Forecast) AverageClouds() float64 {
+	clouds := 0.0
+	for _, w := range f {
+		clouds += w.Clouds
+	}
+	return clouds / float64(len(f))
+}
+
+func (f Forecast) AverageWindSpeed() float64 {
+	speed := 0.0
+	for _, w := range f {
+		speed += w.WindSpeed
+	}
+	return speed / float64(len(f))
+}
+
+func (f Forecast) AveragePressure() float64 {
+	pressure := 0.0
+	for _, w := range f {
+		pressure += w.Pressure
+	}
+	return pressure / float64(len(f))
+}
+
+func (f Forecast) AverageFeelsLike() float64 {
+	temp := 0.0
+	for _, w := range f {
+		temp += w.FeelsLike
+	}
+	return temp / float64(len(f))
+}
+
+func (f Forecast) MaximumPrecipitationProbability() float64 {
+	max := 0.0
+	for _, w := range f {
+		if w.PrecipitationProbability > max {
+			max = w.PrecipitationProbability
+		}
+	}
+	return max
+}
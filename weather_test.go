@@ -0,0 +1,3256 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// newTestClient returns a client with opts that sends its requests to a test
+// server responding with handler, and a function that shuts the server down.
+func newTestClient(handler http.HandlerFunc, opts ...Option) (Client, func()) {
+	srv := httptest.NewServer(handler)
+	return NewClient(append([]Option{WithAPIKey("test-key"), WithServer(srv.URL+"/", "2.5")}, opts...)...), srv.Close
+}
+
+// respond returns a handler that writes body with a 200 status.
+func respond(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}
+}
+
+func TestStreamWeatherInBox(t *testing.T) {
+	const body = `{"cod":200,"calctime":0.3,"cnt":3,"list":[` +
+		`{"id":1,"name":"A","dt":100,"main":{"temp":1}},` +
+		`{"id":2,"name":"B","dt":200,"main":{"temp":2}},` +
+		`{"id":3,"name":"C","dt":300,"main":{"temp":3}}]}`
+	errStop := errors.New("stop")
+
+	tests := []struct {
+		name     string
+		body     string
+		stopAt   int
+		wantTemp []float64
+		wantErr  error
+	}{
+		{name: "all", body: body, wantTemp: []float64{1, 2, 3}},
+		{name: "skips other keys", body: `{"meta":{"list":[{"main":{"temp":9}}]},"list":[{"main":{"temp":1}}],"more":[1,2]}`, wantTemp: []float64{1}},
+		{name: "stops when fn fails", body: body, stopAt: 2, wantTemp: []float64{1, 2}, wantErr: errStop},
+		{name: "no list", body: `{"cod":200}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, done := newTestClient(respond(tt.body))
+			defer done()
+			var temps []float64
+			err := c.StreamWeatherInBox(context.Background(), BoundingBox{}, 10, func(w Weather) error {
+				temps = append(temps, w.Temperature)
+				if len(temps) == tt.stopAt {
+					return errStop
+				}
+				return nil
+			})
+			if err != tt.wantErr {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if len(temps) != len(tt.wantTemp) {
+				t.Fatalf("got temperatures %v, want %v", temps, tt.wantTemp)
+			}
+			for i := range temps {
+				if temps[i] != tt.wantTemp[i] {
+					t.Fatalf("got temperatures %v, want %v", temps, tt.wantTemp)
+				}
+			}
+		})
+	}
+}
+
+func TestStreamWeatherInBoxTooLarge(t *testing.T) {
+	body := `{"list":[` + strings.Repeat(`{"main":{"temp":1}},`, 100) + `{"main":{"temp":1}}]}`
+	c, done := newTestClient(respond(body), WithMaxResponseBytes(200))
+	defer done()
+
+	calls := 0
+	err := c.StreamWeatherInBox(context.Background(), BoundingBox{}, 10, func(Weather) error {
+		calls++
+		return nil
+	})
+	var tooLarge *ResponseTooLargeError
+	if !errors.As(err, &tooLarge) || tooLarge.Limit != 200 {
+		t.Fatalf("got error %v, want *ResponseTooLargeError with limit 200", err)
+	}
+	if calls > 10 {
+		t.Errorf("fn called %d times, want the stream to stop near the limit", calls)
+	}
+}
+
+func TestStreamWeatherInBoxMetrics(t *testing.T) {
+	c, done := newTestClient(respond(`{"list":[]}`), WithMetrics())
+	defer done()
+	if err := c.StreamWeatherInBox(context.Background(), BoundingBox{}, 10, func(Weather) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if text := c.MetricsText(); !strings.Contains(text, "weather_requests_total 1\n") {
+		t.Errorf("metrics don't count the request:\n%s", text)
+	}
+}
+
+func TestDescribeConditions(t *testing.T) {
+	tests := []struct {
+		name    string
+		weather Weather
+		want    string
+	}{
+		{"unset units are celsius", Weather{Temperature: 20}, "Warm"},
+		{"imperial", Weather{Temperature: 40, Units: Imperial, WindSpeed: 20, ConditionID: 500}, "Cold and breezy with light rain"},
+		{"kelvin", Weather{Temperature: 300, Units: Kelvin, ConditionID: 800}, "Hot with clear skies"},
+		{"windy and freezing", Weather{Temperature: -5, Units: Metric, WindSpeed: 12, ConditionID: 511}, "Freezing and windy with sleet"},
+		{"atmosphere", Weather{Temperature: 15, Units: Metric, ConditionID: 741, Condition: "Fog"}, "Cool with fog"},
+		{"overcast", Weather{Temperature: 15, Units: Metric, ConditionID: 804}, "Cool with overcast skies"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.weather.DescribeConditions(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBestOutdoorDay(t *testing.T) {
+	day := func(n int) time.Time {
+		return time.Date(2020, 6, 1+n, 12, 0, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name     string
+		forecast Forecast
+		want     time.Time
+		wantOK   bool
+	}{
+		{name: "empty"},
+		{
+			name: "mild dry calm sunny day wins",
+			forecast: Forecast{
+				{Date: day(0), TemperatureMax: 31, Units: Metric, PrecipitationProbability: 0.1, WindSpeed: 2, Clouds: 10},
+				{Date: day(1), TemperatureMax: 22, Units: Metric, WindSpeed: 1, Clouds: 5},
+				{Date: day(2), TemperatureMax: 21, Units: Metric, PrecipitationProbability: 0.9, WindSpeed: 3, Clouds: 90},
+				{Date: day(3), TemperatureMax: 20, Units: Metric, WindSpeed: 12, Clouds: 20},
+			},
+			want:   day(1),
+			wantOK: true,
+		},
+		{
+			name: "unset units are celsius",
+			forecast: Forecast{
+				{Date: day(0), TemperatureMax: 5},
+				{Date: day(1), TemperatureMax: 21},
+				{Date: day(2), TemperatureMax: 35},
+			},
+			want:   day(1),
+			wantOK: true,
+		},
+		{
+			name: "imperial",
+			forecast: Forecast{
+				{Date: day(0), TemperatureMax: 95, Units: Imperial},
+				{Date: day(1), TemperatureMax: 70, Units: Imperial},
+			},
+			want:   day(1),
+			wantOK: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.forecast.BestOutdoorDay()
+			if ok != tt.wantOK {
+				t.Fatalf("got ok %v, want %v", ok, tt.wantOK)
+			}
+			if ok && got.Date.Day() != tt.want.Day() {
+				t.Errorf("got day %v, want %v", got.Date, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestCoalescing(t *testing.T) {
+	var hits int32
+	release := make(chan struct{})
+	c, done := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		<-release
+		w.Write([]byte(`{"main":{"temp":7}}`))
+	}, WithRequestCoalescing())
+	defer done()
+
+	const callers = 5
+	var wg sync.WaitGroup
+	temps := make([]float64, callers)
+	errs := make([]error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w, err := c.GetCurrentWeather(context.Background(), "12345")
+			temps[i], errs[i] = w.Temperature, err
+		}(i)
+	}
+	// Give every caller time to join the call in flight.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&hits); n != 1 {
+		t.Errorf("server got %d requests, want 1", n)
+	}
+	for i := range temps {
+		if errs[i] != nil || temps[i] != 7 {
+			t.Errorf("caller %d got %v, %v, want 7, nil", i, temps[i], errs[i])
+		}
+	}
+}
+
+func TestRequestCoalescingPerServer(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	c, doneA := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte(`{"main":{"temp":1}}`))
+	}, WithRequestCoalescing())
+	defer doneA()
+	srvB := httptest.NewServer(respond(`{"main":{"temp":2}}`))
+	defer srvB.Close()
+	other := c.With(WithServer(srvB.URL+"/", "2.5"))
+
+	result := make(chan float64, 1)
+	go func() {
+		w, _ := c.GetCurrentWeather(context.Background(), "12345")
+		result <- w.Temperature
+	}()
+	<-started
+
+	otherResult := make(chan float64, 1)
+	go func() {
+		w, _ := other.GetCurrentWeather(context.Background(), "12345")
+		otherResult <- w.Temperature
+	}()
+	select {
+	case temp := <-otherResult:
+		if temp != 2 {
+			t.Errorf("other server: got %v, want 2", temp)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("request to other server joined the call in flight to the first")
+	}
+	close(release)
+	if temp := <-result; temp != 1 {
+		t.Errorf("first server: got %v, want 1", temp)
+	}
+}
+
+func TestToTimeSeriesSupportsNumericSnapshotKeys(t *testing.T) {
+	f := Forecast{{Date: time.Unix(100, 0), SeaLevelPressure: 1013, ConditionID: 500}}
+	for key, v := range f[0].Snapshot() {
+		switch v.(type) {
+		case float64, int:
+		default:
+			continue
+		}
+		if _, _, err := f.ToTimeSeries(key); err != nil {
+			t.Errorf("ToTimeSeries(%q): %v", key, err)
+		}
+	}
+
+	_, values, err := f.ToTimeSeries("sea_level_pressure")
+	if err != nil || len(values) != 1 || values[0] != 1013 {
+		t.Errorf("ToTimeSeries(sea_level_pressure) = %v, %v, want [1013]", values, err)
+	}
+	if _, _, err := f.ToTimeSeries("units"); err == nil {
+		t.Error("ToTimeSeries(units): got nil error")
+	}
+}
+
+func TestDailyWith(t *testing.T) {
+	day := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	f := Forecast{
+		{Date: day.Add(3 * time.Hour), Temperature: 1, TemperatureMin: 0, TemperatureMax: 2, Humidity: 80, PrecipitationProbability: 0.2},
+		{Date: day.Add(9 * time.Hour), Temperature: 2, TemperatureMin: 1, TemperatureMax: 3, Humidity: 60},
+		{Date: day.Add(15 * time.Hour), Temperature: 30, TemperatureMin: 28, TemperatureMax: 33, Humidity: 40, PrecipitationProbability: 0.6},
+		{Date: day.Add(27 * time.Hour), Temperature: 5, TemperatureMin: 4, TemperatureMax: 6, Humidity: 90},
+	}
+	median := func(f Forecast) float64 {
+		temps := make([]float64, 0, len(f))
+		for _, w := range f {
+			temps = append(temps, w.Temperature)
+		}
+		sort.Float64s(temps)
+		mid := len(temps) / 2
+		if len(temps)%2 == 0 {
+			return (temps[mid-1] + temps[mid]) / 2
+		}
+		return temps[mid]
+	}
+
+	tests := []struct {
+		name string
+		agg  DailyAggregator
+		want Weather
+	}{
+		{
+			name: "defaults",
+			agg:  DefaultDailyAggregator(),
+			want: Weather{Temperature: 11, TemperatureMin: 0, TemperatureMax: 33, Humidity: 60, PrecipitationProbability: 0.6},
+		},
+		{
+			name: "median temperature, defaults elsewhere",
+			agg:  DailyAggregator{Temperature: median},
+			want: Weather{Temperature: 2, TemperatureMin: 0, TemperatureMax: 33, Humidity: 60, PrecipitationProbability: 0.6},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			daily := f.DailyWith(tt.agg)
+			if len(daily) != 2 {
+				t.Fatalf("got %d days, want 2", len(daily))
+			}
+			got := daily[0]
+			if !got.Date.Equal(day) || got.Temperature != tt.want.Temperature ||
+				got.TemperatureMin != tt.want.TemperatureMin || got.TemperatureMax != tt.want.TemperatureMax ||
+				got.Humidity != tt.want.Humidity || got.PrecipitationProbability != tt.want.PrecipitationProbability {
+				t.Errorf("got %+v, want %+v on %v", got, tt.want, day)
+			}
+		})
+	}
+}
+
+func TestDefaultDailyAggregatorIsACopy(t *testing.T) {
+	agg := DefaultDailyAggregator()
+	agg.Temperature = nil
+	f := Forecast{{Date: time.Unix(0, 0), Temperature: 3}}
+	if got := f.Daily(); got[0].Temperature != 3 {
+		t.Errorf("Daily temperature = %v, want 3", got[0].Temperature)
+	}
+	if DefaultDailyAggregator().Temperature == nil {
+		t.Error("changing the returned aggregator changed the default")
+	}
+}
+
+func TestDailyUsesFirstEntryLocation(t *testing.T) {
+	east := time.FixedZone("UTC+10", 10*60*60)
+	// 20:00 UTC on June 1 is 06:00 on June 2 in UTC+10, so both entries fall
+	// on June 2 in the location of the first.
+	f := Forecast{
+		{Date: time.Date(2020, 6, 2, 3, 0, 0, 0, east), Temperature: 10},
+		{Date: time.Date(2020, 6, 1, 20, 0, 0, 0, time.UTC), Temperature: 20},
+	}
+	daily := f.Daily()
+	if len(daily) != 1 {
+		t.Fatalf("got %d days, want 1: %+v", len(daily), daily)
+	}
+	if want := time.Date(2020, 6, 2, 0, 0, 0, 0, east); !daily[0].Date.Equal(want) {
+		t.Errorf("got date %v, want %v", daily[0].Date, want)
+	}
+	if daily[0].Temperature != 15 {
+		t.Errorf("got temperature %v, want 15", daily[0].Temperature)
+	}
+	if got := (Forecast{}).Daily(); len(got) != 0 {
+		t.Errorf("empty forecast: got %v", got)
+	}
+}
+
+func TestDailyInSameLocationMatchesDaily(t *testing.T) {
+	east := time.FixedZone("UTC+10", 10*60*60)
+	tests := []struct {
+		name string
+		f    Forecast
+	}{
+		{"empty", Forecast{}},
+		{"one location", Forecast{
+			{Date: time.Date(2020, 6, 1, 3, 0, 0, 0, east), Temperature: 10},
+			{Date: time.Date(2020, 6, 1, 21, 0, 0, 0, east), Temperature: 20},
+			{Date: time.Date(2020, 6, 2, 9, 0, 0, 0, east), Temperature: 30},
+		}},
+		{"mixed locations", Forecast{
+			{Date: time.Date(2020, 6, 2, 3, 0, 0, 0, east), Temperature: 10},
+			{Date: time.Date(2020, 6, 1, 20, 0, 0, 0, time.UTC), Temperature: 20},
+			{Date: time.Date(2020, 6, 2, 20, 0, 0, 0, time.UTC), Temperature: 30},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			daily, in := tt.f.Daily(), tt.f.DailyIn(east)
+			if len(daily) != len(in) {
+				t.Fatalf("Daily() = %v, DailyIn() = %v", daily, in)
+			}
+			for i := range daily {
+				if daily[i] != in[i] {
+					t.Errorf("day %d: Daily() = %+v, DailyIn() = %+v", i, daily[i], in[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDailyHelpersGroupLikeDaily(t *testing.T) {
+	east := time.FixedZone("UTC+10", 10*60*60)
+	// Both entries fall on June 2 in UTC+10, the first entry's location, but
+	// the second is on June 1 in its own location.
+	late := time.Date(2020, 6, 1, 20, 0, 0, 0, time.UTC)
+	f := Forecast{
+		{Date: time.Date(2020, 6, 2, 3, 0, 0, 0, east), FeelsLike: 20, TemperatureMin: 15, TemperatureMax: 20, WindSpeed: 2, PrecipitationProbability: 0.1},
+		{Date: late, FeelsLike: 25, TemperatureMin: 18, TemperatureMax: 26, WindSpeed: 5, PrecipitationProbability: 0.4},
+	}
+
+	day, ok := f.ApparentHottestDay()
+	if !ok {
+		t.Fatal("ApparentHottestDay() ok = false, want true")
+	}
+	if want := time.Date(2020, 6, 2, 0, 0, 0, 0, east); !day.Date.Equal(want) {
+		t.Errorf("ApparentHottestDay() = %v, want %v", day.Date, want)
+	}
+
+	if got := f.DailyTempRanges(); len(got) != 1 || got["20200602"] != 11 {
+		t.Errorf("DailyTempRanges() = %v, want 20200602: 11", got)
+	}
+	if got := f.ChanceOfRainByDay(); len(got) != 1 || got["20200602"] != 0.4 {
+		t.Errorf("ChanceOfRainByDay() = %v, want 20200602: 0.4", got)
+	}
+	if got := f.PeakWindPerDay(); len(got) != 1 || !got[0].Date.Equal(late) {
+		t.Errorf("PeakWindPerDay() = %v, want only the entry at %v", got, late)
+	}
+}
+
+func TestWithServerVersion(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Write([]byte(`{"date":"2020-03-04"}`))
+	}))
+	defer srv.Close()
+	c := NewClient(WithAPIKey("k"), WithServer(srv.URL, "2.6"))
+
+	c.GetCurrentWeather(context.Background(), "12345")
+	c.GetForecast(context.Background(), "12345")
+	c.GetDaySummary(context.Background(), 1, 2, time.Date(2020, 3, 4, 0, 0, 0, 0, time.UTC))
+
+	want := []string{"/data/2.6/weather", "/data/2.6/forecast", "/data/3.0/onecall/day_summary"}
+	if strings.Join(paths, " ") != strings.Join(want, " ") {
+		t.Errorf("got paths %v, want %v", paths, want)
+	}
+}
+
+func TestHumidex(t *testing.T) {
+	tests := []struct {
+		name    string
+		weather Weather
+		want    float64
+	}{
+		// Environment Canada's humidex table gives 41 for 30°C with a dew
+		// point of 24°C, which 70% humidity gives.
+		{"reference", Weather{Temperature: 30, Humidity: 70}, 41},
+		{"dry heat", Weather{Temperature: 35, Humidity: 40}, 42},
+		{"below 20°C", Weather{Temperature: 15, Humidity: 90}, 15},
+		{"no humidity", Weather{Temperature: 30}, 30},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.weather.Humidex(); math.Abs(got-tt.want) > 0.5 {
+				t.Errorf("got %.2f, want %v within 0.5", got, tt.want)
+			}
+		})
+	}
+}
+
+// zipHandler responds to current weather requests with the zip code as the
+// temperature, or 404 for the zip code "bad". Requests for the zip code
+// "slow" wait until they are cancelled.
+func zipHandler(w http.ResponseWriter, r *http.Request) {
+	switch zip := r.URL.Query().Get("zip"); zip {
+	case "bad":
+		w.WriteHeader(http.StatusNotFound)
+	case "slow":
+		<-r.Context().Done()
+	default:
+		fmt.Fprintf(w, `{"main":{"temp":%s}}`, zip)
+	}
+}
+
+func TestGetCurrentWeatherBatchFailFast(t *testing.T) {
+	c, done := newTestClient(zipHandler)
+	defer done()
+
+	got, err := c.GetCurrentWeatherBatchFailFast(context.Background(), []string{"3", "1", "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []float64{3, 1, 2} {
+		if got[i].Temperature != want {
+			t.Errorf("result %d: got %v, want %v", i, got[i].Temperature, want)
+		}
+	}
+
+	start := time.Now()
+	_, err = c.GetCurrentWeatherBatchFailFast(context.Background(), []string{"slow", "1", "bad", "slow"})
+	if !errors.Is(err, ErrCityNotFound) {
+		t.Errorf("got error %v, want ErrCityNotFound", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("took %v, want the slow requests cancelled promptly", elapsed)
+	}
+}
+
+func TestChanceOfRainByDay(t *testing.T) {
+	day := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	f := Forecast{
+		{Date: day.Add(3 * time.Hour), PrecipitationProbability: 0.2},
+		{Date: day.Add(9 * time.Hour), PrecipitationProbability: 0.7},
+		{Date: day.Add(15 * time.Hour), PrecipitationProbability: 0.4},
+		{Date: day.Add(27 * time.Hour), PrecipitationProbability: 0},
+	}
+
+	got := f.ChanceOfRainByDay()
+	want := map[string]float64{"20200601": 0.7, "20200602": 0}
+	if len(got) != len(want) {
+		t.Fatalf("ChanceOfRainByDay() = %v, want %v", got, want)
+	}
+	for day, chance := range want {
+		if got[day] != chance {
+			t.Errorf("ChanceOfRainByDay()[%q] = %v, want %v", day, got[day], chance)
+		}
+	}
+
+	if got := (Forecast{}).ChanceOfRainByDay(); len(got) != 0 {
+		t.Errorf("ChanceOfRainByDay() of an empty forecast = %v, want empty", got)
+	}
+}
+
+func TestPrecipitationType(t *testing.T) {
+	tests := []struct {
+		id   int
+		want string
+	}{
+		{200, "rain"},
+		{211, "none"},
+		{301, "rain"},
+		{500, "rain"},
+		{511, "sleet"},
+		{600, "snow"},
+		{611, "sleet"},
+		{615, "mixed"},
+		{622, "snow"},
+		{741, "none"},
+		{800, "none"},
+		{0, "none"},
+	}
+	for _, tt := range tests {
+		if got := (Weather{ConditionID: tt.id}).PrecipitationType(); got != tt.want {
+			t.Errorf("PrecipitationType() for %d = %q, want %q", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestConditionID(t *testing.T) {
+	c, done := newTestClient(respond(`{"weather":[{"id":502,"main":"Rain"},{"id":701,"main":"Mist"}],"main":{"temp":1}}`))
+	defer done()
+
+	w, err := c.GetCurrentWeather(context.Background(), "12345")
+	if err != nil {
+		t.Fatalf("GetCurrentWeather() error = %v", err)
+	}
+	if w.ConditionID != 502 {
+		t.Errorf("ConditionID = %d, want 502", w.ConditionID)
+	}
+
+	tests := []struct {
+		id   int
+		want string
+	}{
+		{202, "Thunderstorm"},
+		{311, "Drizzle"},
+		{502, "Rain"},
+		{601, "Snow"},
+		{781, "Atmosphere"},
+		{800, "Clear"},
+		{804, "Clouds"},
+		{0, ""},
+	}
+	for _, tt := range tests {
+		if got := (Weather{ConditionID: tt.id}).ConditionGroup(); got != tt.want {
+			t.Errorf("ConditionGroup() for %d = %q, want %q", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestResolution(t *testing.T) {
+	at := func(hours ...int) Forecast {
+		var f Forecast
+		for _, h := range hours {
+			f = append(f, Weather{Date: time.Unix(0, 0).Add(time.Duration(h) * time.Hour)})
+		}
+		return f
+	}
+
+	tests := []struct {
+		name string
+		f    Forecast
+		want time.Duration
+	}{
+		{"empty", nil, 0},
+		{"single", at(0), 0},
+		{"regular", at(0, 3, 6, 9), 3 * time.Hour},
+		{"unsorted", at(9, 0, 6, 3), 3 * time.Hour},
+		{"gap", at(0, 3, 6, 18), 3 * time.Hour},
+		{"even gaps", at(0, 1, 4, 10, 11), 2 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.f.Resolution(); got != tt.want {
+				t.Errorf("Resolution() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetCurrentWeatherAt(t *testing.T) {
+	tests := []struct {
+		name string
+		loc  Locator
+		want string
+	}{
+		{"zip", ZipCode("12345,us"), "zip=12345%2Cus"},
+		{"city name", CityName("London,uk"), "q=London%2Cuk"},
+		{"city id", CityID(2643743), "id=2643743"},
+		{"coords", Coords{Lat: 51.5, Lon: -0.12}, "lat=51.5&lon=-0.12"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var query string
+			c, done := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+				q := r.URL.Query()
+				q.Del("APPID")
+				q.Del("units")
+				query = q.Encode()
+				w.Write([]byte(`{"main":{"temp":1}}`))
+			})
+			defer done()
+
+			if _, err := c.GetCurrentWeatherAt(context.Background(), tt.loc); err != nil {
+				t.Fatalf("GetCurrentWeatherAt() error = %v", err)
+			}
+			if query != tt.want {
+				t.Errorf("query = %q, want %q", query, tt.want)
+			}
+		})
+	}
+}
+
+func TestEstimatedSunHours(t *testing.T) {
+	day := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	sunny := func(d time.Time, clouds float64) Weather {
+		return Weather{Date: d.Add(12 * time.Hour), Sunrise: d.Add(6 * time.Hour), Sunset: d.Add(18 * time.Hour), Clouds: clouds}
+	}
+
+	tests := []struct {
+		name string
+		f    Forecast
+		want float64
+	}{
+		{"empty", nil, 0},
+		{"clear", Forecast{sunny(day, 0)}, 12},
+		{"half cloudy", Forecast{sunny(day, 50)}, 6},
+		{"overcast", Forecast{sunny(day, 100)}, 0},
+		{"two days", Forecast{sunny(day, 0), sunny(day.AddDate(0, 0, 1), 75)}, 15},
+		{"no sun data", Forecast{{Date: day, Clouds: 0}}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.f.EstimatedSunHours(); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("EstimatedSunHours() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetWeatherBundle(t *testing.T) {
+	tests := []struct {
+		name         string
+		forecastCode int
+		wantErr      bool
+	}{
+		{"both succeed", http.StatusOK, false},
+		{"forecast fails", http.StatusNotFound, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, done := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasSuffix(r.URL.Path, "/forecast") {
+					w.WriteHeader(tt.forecastCode)
+					w.Write([]byte(`{"list":[{"dt":100,"main":{"temp":2}},{"dt":200,"main":{"temp":3}}]}`))
+					return
+				}
+				w.Write([]byte(`{"main":{"temp":1}}`))
+			})
+			defer done()
+
+			current, forecast, err := c.GetWeatherBundle(context.Background(), "12345")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("GetWeatherBundle() error = nil, want an error")
+				}
+				if current != (Weather{}) || forecast != nil {
+					t.Errorf("GetWeatherBundle() = %v, %v, want zero values on error", current, forecast)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetWeatherBundle() error = %v", err)
+			}
+			if current.Temperature != 1 {
+				t.Errorf("current temperature = %v, want 1", current.Temperature)
+			}
+			if len(forecast) != 2 || forecast[0].Temperature != 2 {
+				t.Errorf("forecast = %v, want two entries starting at 2", forecast)
+			}
+		})
+	}
+}
+
+func TestBeaufort(t *testing.T) {
+	tests := []struct {
+		speed float64
+		units Units
+		want  int
+		name  string
+	}{
+		{0, Metric, 0, "Calm"},
+		{0.5, Metric, 1, "Light air"},
+		{5, Metric, 3, "Gentle breeze"},
+		{17.2, Metric, 8, "Gale"},
+		{40, Metric, 12, "Hurricane force"},
+		{10, Imperial, 3, "Gentle breeze"},
+	}
+	for _, tt := range tests {
+		w := Weather{WindSpeed: tt.speed, Units: tt.units}
+		if got := w.Beaufort(); got != tt.want {
+			t.Errorf("Beaufort() for %v %s = %d, want %d", tt.speed, tt.units, got, tt.want)
+		}
+		if got := w.BeaufortName(); got != tt.name {
+			t.Errorf("BeaufortName() for %v %s = %q, want %q", tt.speed, tt.units, got, tt.name)
+		}
+	}
+}
+
+func TestWithFractionalHumidity(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want float64
+	}{
+		{"percentage", nil, 65},
+		{"fraction", []Option{WithFractionalHumidity()}, 0.65},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, done := newTestClient(respond(`{"main":{"temp":1,"humidity":65}}`), tt.opts...)
+			defer done()
+
+			w, err := c.GetCurrentWeather(context.Background(), "12345")
+			if err != nil {
+				t.Fatalf("GetCurrentWeather() error = %v", err)
+			}
+			if w.Humidity != tt.want {
+				t.Errorf("Humidity = %v, want %v", w.Humidity, tt.want)
+			}
+		})
+	}
+}
+
+func TestLongestStreaks(t *testing.T) {
+	hourly := func(ids ...int) Forecast {
+		var f Forecast
+		for i, id := range ids {
+			f = append(f, Weather{Date: time.Unix(0, 0).Add(time.Duration(i) * time.Hour), ConditionID: id})
+		}
+		return f
+	}
+	// rained sets a Rain volume on the entries at the given indexes.
+	rained := func(f Forecast, indexes ...int) Forecast {
+		for _, i := range indexes {
+			f[i].Rain = 0.5
+		}
+		return f
+	}
+
+	tests := []struct {
+		name    string
+		f       Forecast
+		wantDry time.Duration
+		wantWet time.Duration
+	}{
+		{"empty", nil, 0, 0},
+		{"all dry", hourly(800, 801, 800), 3 * time.Hour, 0},
+		{"all wet", hourly(500, 600, 501), 0, 3 * time.Hour},
+		{"mixed", hourly(800, 500, 501, 800, 800, 800, 600), 3 * time.Hour, 2 * time.Hour},
+		{"dry thunderstorm", hourly(211, 800, 500), 2 * time.Hour, time.Hour},
+		{"rain under cloud conditions", rained(hourly(800, 803, 803, 800), 1, 2), time.Hour, 2 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.f.LongestDryStreak(); got != tt.wantDry {
+				t.Errorf("LongestDryStreak() = %v, want %v", got, tt.wantDry)
+			}
+			if got := tt.f.LongestWetStreak(); got != tt.wantWet {
+				t.Errorf("LongestWetStreak() = %v, want %v", got, tt.wantWet)
+			}
+		})
+	}
+}
+
+func TestWithDefaultLocation(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		zip  string
+		want string
+	}{
+		{"explicit zip", []Option{WithDefaultLocation("11111")}, "22222", "22222"},
+		{"default zip", []Option{WithDefaultLocation("11111")}, "", "11111"},
+		{"no default", nil, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			c, done := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+				got = r.URL.Query().Get("zip")
+				w.Write([]byte(`{"main":{"temp":1}}`))
+			}, tt.opts...)
+			defer done()
+
+			if _, err := c.GetCurrentWeather(context.Background(), tt.zip); err != nil {
+				t.Fatalf("GetCurrentWeather() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("zip = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToImperialAndToMetric(t *testing.T) {
+	metric := Weather{Temperature: 100, WindSpeed: 10, Units: Metric}
+
+	imperial := metric.ToImperial()
+	if imperial.Units != Imperial {
+		t.Errorf("ToImperial().Units = %q, want %q", imperial.Units, Imperial)
+	}
+	if math.Abs(imperial.Temperature-212) > 1e-9 {
+		t.Errorf("ToImperial().Temperature = %v, want 212", imperial.Temperature)
+	}
+
+	back := imperial.ToMetric()
+	if back.Units != Metric {
+		t.Errorf("ToMetric().Units = %q, want %q", back.Units, Metric)
+	}
+	if math.Abs(back.Temperature-100) > 1e-9 || math.Abs(back.WindSpeed-10) > 1e-9 {
+		t.Errorf("ToMetric() = %+v, want the original values", back)
+	}
+
+	unset := Weather{Temperature: 20}
+	if got := unset.ToImperial(); got != unset {
+		t.Errorf("ToImperial() with no units = %+v, want it unchanged", got)
+	}
+}
+
+func TestForecastLatLon(t *testing.T) {
+	c, done := newTestClient(respond(`{"list":[{"dt":100,"main":{"temp":1}},{"dt":200,"main":{"temp":2}}],` +
+		`"city":{"coord":{"lat":51.5,"lon":-0.12}}}`))
+	defer done()
+
+	f, err := c.GetForecast(context.Background(), "12345")
+	if err != nil {
+		t.Fatalf("GetForecast() error = %v", err)
+	}
+	for i, w := range append(f, f.Daily()...) {
+		if w.Lat != 51.5 || w.Lon != -0.12 {
+			t.Errorf("entry %d at %v, %v, want 51.5, -0.12", i, w.Lat, w.Lon)
+		}
+	}
+}
+
+func TestCurrentWeatherLatLon(t *testing.T) {
+	c, done := newTestClient(respond(`{"main":{"temp":1},"coord":{"lat":51.5,"lon":-0.12}}`))
+	defer done()
+
+	w, err := c.GetCurrentWeather(context.Background(), "12345")
+	if err != nil {
+		t.Fatalf("GetCurrentWeather() error = %v", err)
+	}
+	if w.Lat != 51.5 || w.Lon != -0.12 {
+		t.Errorf("got %v, %v, want 51.5, -0.12", w.Lat, w.Lon)
+	}
+}
+
+func TestGetForecastFor(t *testing.T) {
+	const body = `{"list":[{"dt":0,"main":{"temp":1}},{"dt":10800,"main":{"temp":2}},{"dt":21600,"main":{"temp":3}}]}`
+	now := func() time.Time { return time.Unix(0, 0) }
+
+	tests := []struct {
+		name    string
+		horizon time.Duration
+		want    int
+	}{
+		{"now only", 0, 1},
+		{"exactly at an entry", 3 * time.Hour, 2},
+		{"between entries", 5 * time.Hour, 2},
+		{"past the end", 24 * time.Hour, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, done := newTestClient(respond(body), WithClock(now))
+			defer done()
+
+			f, err := c.GetForecastFor(context.Background(), "12345", tt.horizon)
+			if err != nil {
+				t.Fatalf("GetForecastFor() error = %v", err)
+			}
+			if len(f) != tt.want {
+				t.Errorf("got %d entries, want %d", len(f), tt.want)
+			}
+		})
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	date := time.Unix(100, 0)
+	w := Weather{Date: date, Temperature: 21.5, ConditionID: 800, Condition: "Clear", Units: Metric, Lat: 1, Lon: 2}
+	snap := w.Snapshot()
+
+	keys := []string{
+		"date", "temperature", "temperature_min", "temperature_max", "feels_like",
+		"humidity", "pressure", "sea_level_pressure", "ground_level_pressure",
+		"precipitation_probability", "condition_id", "condition", "clouds", "rain",
+		"sunrise", "sunset", "part_of_day", "wind_speed", "wind_direction", "units",
+		"lat", "lon",
+	}
+	if len(snap) != len(keys) {
+		t.Errorf("Snapshot() has %d keys, want %d", len(snap), len(keys))
+	}
+	for _, k := range keys {
+		if _, ok := snap[k]; !ok {
+			t.Errorf("Snapshot() is missing %q", k)
+		}
+	}
+
+	tests := []struct {
+		key  string
+		want interface{}
+	}{
+		{"date", date},
+		{"temperature", 21.5},
+		{"condition_id", 800},
+		{"condition", "Clear"},
+		{"units", "metric"},
+		{"lat", 1.0},
+	}
+	for _, tt := range tests {
+		if got := snap[tt.key]; got != tt.want {
+			t.Errorf("Snapshot()[%q] = %#v, want %#v", tt.key, got, tt.want)
+		}
+	}
+
+	snap["temperature"] = 0.0
+	if w.Temperature != 21.5 {
+		t.Error("changing the snapshot changed the Weather")
+	}
+}
+
+func TestWithForecastTimeout(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		path string
+		want time.Duration
+	}{
+		{"default", nil, "data/2.5/forecast", 5 * time.Second},
+		{"forecast", []Option{WithForecastTimeout(time.Minute)}, "data/2.5/forecast", time.Minute},
+		{"other endpoint", []Option{WithForecastTimeout(time.Minute)}, "data/2.5/weather", 5 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewClient(tt.opts...).requestTimeout(tt.path); got != tt.want {
+				t.Errorf("requestTimeout(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+
+	c, done := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/forecast") {
+			<-r.Context().Done()
+			return
+		}
+		w.Write([]byte(`{"main":{"temp":1}}`))
+	}, WithForecastTimeout(20*time.Millisecond))
+	defer done()
+
+	if _, err := c.GetForecast(context.Background(), "12345"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("GetForecast() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if _, err := c.GetCurrentWeather(context.Background(), "12345"); err != nil {
+		t.Errorf("GetCurrentWeather() error = %v", err)
+	}
+}
+
+func TestTemperatureHistogram(t *testing.T) {
+	f := Forecast{{Temperature: -3}, {Temperature: 0}, {Temperature: 4.9}, {Temperature: 5}, {Temperature: 12}}
+
+	tests := []struct {
+		name     string
+		binWidth float64
+		want     map[float64]int
+	}{
+		{"width 5", 5, map[float64]int{-5: 1, 0: 2, 5: 1, 10: 1}},
+		{"width 10", 10, map[float64]int{-10: 1, 0: 3, 10: 1}},
+		{"zero width", 0, nil},
+		{"negative width", -1, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := f.TemperatureHistogram(tt.binWidth)
+			if tt.want == nil {
+				if got != nil {
+					t.Errorf("TemperatureHistogram() = %v, want nil", got)
+				}
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("TemperatureHistogram() = %v, want %v", got, tt.want)
+			}
+			for bin, n := range tt.want {
+				if got[bin] != n {
+					t.Errorf("TemperatureHistogram()[%v] = %d, want %d", bin, got[bin], n)
+				}
+			}
+		})
+	}
+}
+
+func TestForecastAccuracy(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []Option
+		predicted Weather
+		want      float64
+	}{
+		{"same units", []Option{WithUnits(Metric)}, Weather{Temperature: 18, Units: Metric}, 2},
+		{"predicted higher", []Option{WithUnits(Metric)}, Weather{Temperature: 23, Units: Metric}, 3},
+		{"converted", []Option{WithUnits(Metric)}, Weather{Temperature: 68, Units: Imperial}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, done := newTestClient(respond(`{"main":{"temp":20}}`), tt.opts...)
+			defer done()
+
+			got, err := c.ForecastAccuracy(context.Background(), "12345", tt.predicted)
+			if err != nil {
+				t.Fatalf("ForecastAccuracy() error = %v", err)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("ForecastAccuracy() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDataPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []Option
+		version  string
+		endpoint string
+		want     string
+	}{
+		{"default 2.5", nil, "2.5", "weather", "data/2.5/weather"},
+		{"default 3.0", nil, "3.0", "onecall/day_summary", "data/3.0/onecall/day_summary"},
+		{"server version", []Option{WithServer("http://example.com/", "2.6")}, "2.5", "forecast", "data/2.6/forecast"},
+		{"server version keeps 3.0", []Option{WithServer("http://example.com/", "2.6")}, "3.0", "onecall/day_summary", "data/3.0/onecall/day_summary"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewClient(tt.opts...).dataPath(tt.version, tt.endpoint); got != tt.want {
+				t.Errorf("dataPath(%q, %q) = %q, want %q", tt.version, tt.endpoint, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithAPIKeyFromEnv(t *testing.T) {
+	const varName = "WEATHER_TEST_APIKEY"
+	defer os.Unsetenv(varName)
+
+	tests := []struct {
+		name    string
+		value   string
+		set     bool
+		wantErr error
+	}{
+		{"set", "env-key", true, nil},
+		{"unset", "", false, ErrNoAPIKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Unsetenv(varName)
+			if tt.set {
+				os.Setenv(varName, tt.value)
+			}
+
+			var got string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.URL.Query().Get("APPID")
+				w.Write([]byte(`{"main":{"temp":1}}`))
+			}))
+			defer srv.Close()
+			c := NewClient(WithServer(srv.URL, "2.5"), WithAPIKeyFromEnv(varName))
+
+			_, err := c.GetCurrentWeather(context.Background(), "12345")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetCurrentWeather() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.value {
+				t.Errorf("APPID = %q, want %q", got, tt.value)
+			}
+		})
+	}
+}
+
+func TestWindDirectionCardinal(t *testing.T) {
+	tests := []struct {
+		deg  float64
+		want string
+	}{
+		{0, "N"},
+		{11.24, "N"},
+		{11.25, "NNE"},
+		{45, "NE"},
+		{90, "E"},
+		{180, "S"},
+		{270, "W"},
+		{348.75, "N"},
+		{360, "N"},
+		{-90, "W"},
+		{450, "E"},
+	}
+	for _, tt := range tests {
+		if got := (Weather{WindDirection: tt.deg}).WindDirectionCardinal(); got != tt.want {
+			t.Errorf("WindDirectionCardinal() for %v° = %q, want %q", tt.deg, got, tt.want)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	f := Forecast{{Temperature: 1}, {Temperature: 5}, {Temperature: 3}, {Temperature: 7}}
+
+	tests := []struct {
+		name string
+		pred func(Weather) bool
+		want []float64
+	}{
+		{"some", func(w Weather) bool { return w.Temperature > 2 }, []float64{5, 3, 7}},
+		{"all", func(Weather) bool { return true }, []float64{1, 5, 3, 7}},
+		{"none", func(Weather) bool { return false }, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := f.Filter(tt.pred)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Filter() = %v, want temperatures %v", got, tt.want)
+			}
+			for i, temp := range tt.want {
+				if got[i].Temperature != temp {
+					t.Errorf("Filter()[%d].Temperature = %v, want %v", i, got[i].Temperature, temp)
+				}
+			}
+		})
+	}
+}
+
+func TestMap(t *testing.T) {
+	f := Forecast{{Temperature: 1}, {Temperature: 2}}
+
+	got := f.Map(func(w Weather) Weather {
+		w.Temperature *= 10
+		return w
+	})
+	if len(got) != 2 || got[0].Temperature != 10 || got[1].Temperature != 20 {
+		t.Errorf("Map() = %v, want temperatures 10 and 20", got)
+	}
+	if f[0].Temperature != 1 {
+		t.Errorf("Map() changed the original forecast to %v", f)
+	}
+	if got := (Forecast{}).Map(func(w Weather) Weather { return w }); len(got) != 0 {
+		t.Errorf("Map() of an empty forecast = %v, want empty", got)
+	}
+}
+
+func TestGetForecastResilient(t *testing.T) {
+	tests := []struct {
+		name      string
+		zipStatus int
+		want      []string
+		wantErr   bool
+	}{
+		{"zip found", http.StatusOK, []string{"zip=12345"}, false},
+		{"falls back to coords", http.StatusNotFound, []string{"zip=12345", "lat=1.5&lon=2.5"}, false},
+		{"other errors", http.StatusInternalServerError, []string{"zip=12345"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var queries []string
+			c, done := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+				q := r.URL.Query()
+				q.Del("APPID")
+				q.Del("units")
+				queries = append(queries, q.Encode())
+				if q.Get("zip") != "" {
+					w.WriteHeader(tt.zipStatus)
+				}
+				w.Write([]byte(`{"list":[{"dt":100,"main":{"temp":1}}]}`))
+			})
+			defer done()
+
+			f, err := c.GetForecastResilient(context.Background(), "12345", 1.5, 2.5)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetForecastResilient() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && len(f) != 1 {
+				t.Errorf("got %d entries, want 1", len(f))
+			}
+			if strings.Join(queries, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("queries = %v, want %v", queries, tt.want)
+			}
+		})
+	}
+}
+
+func TestPressureTrend(t *testing.T) {
+	hourly := func(pressures ...float64) Forecast {
+		var f Forecast
+		for i, p := range pressures {
+			f = append(f, Weather{Date: time.Unix(0, 0).Add(time.Duration(i) * 3 * time.Hour), Pressure: p})
+		}
+		return f
+	}
+
+	tests := []struct {
+		name string
+		f    Forecast
+		want string
+	}{
+		{"empty", nil, "steady"},
+		{"single", hourly(1010), "steady"},
+		{"rising", hourly(1000, 1003, 1006), "rising"},
+		{"falling", hourly(1006, 1003, 1000), "falling"},
+		{"steady", hourly(1010, 1010.5, 1010), "steady"},
+		{"ignores later entries", hourly(1010, 1010, 1010, 900, 800), "steady"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.f.PressureTrend(); got != tt.want {
+				t.Errorf("PressureTrend() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetCurrentWeatherAllUnits(t *testing.T) {
+	requests := 0
+	c, done := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"main":{"temp":100}}`))
+	}, WithUnits(Metric))
+	defer done()
+
+	got, err := c.GetCurrentWeatherAllUnits(context.Background(), "12345")
+	if err != nil {
+		t.Fatalf("GetCurrentWeatherAllUnits() error = %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("made %d requests, want 1", requests)
+	}
+
+	want := map[Units]float64{Metric: 100, Imperial: 212, Kelvin: 373.15}
+	for units, temp := range want {
+		w, ok := got[units]
+		if !ok {
+			t.Errorf("missing %s", units)
+			continue
+		}
+		if w.Units != units || math.Abs(w.Temperature-temp) > 1e-9 {
+			t.Errorf("%s: got %v %s, want %v", units, w.Temperature, w.Units, temp)
+		}
+	}
+}
+
+func TestWithMaxResponseBytes(t *testing.T) {
+	body := `{"main":{"temp":1},"name":"` + strings.Repeat("x", 100) + `"}`
+
+	tests := []struct {
+		name   string
+		status int
+		opts   []Option
+		want   int64
+	}{
+		{"default limit", http.StatusOK, nil, 0},
+		{"under limit", http.StatusOK, []Option{WithMaxResponseBytes(int64(len(body)))}, 0},
+		{"over limit", http.StatusOK, []Option{WithMaxResponseBytes(50)}, 50},
+		{"error body over limit", http.StatusInternalServerError, []Option{WithMaxResponseBytes(50)}, 50},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, done := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(body))
+			}, tt.opts...)
+			defer done()
+
+			_, err := c.GetCurrentWeather(context.Background(), "12345")
+			if tt.want == 0 {
+				if err != nil {
+					t.Fatalf("GetCurrentWeather() error = %v", err)
+				}
+				return
+			}
+			var tooLarge *ResponseTooLargeError
+			if !errors.As(err, &tooLarge) || tooLarge.Limit != tt.want {
+				t.Errorf("GetCurrentWeather() error = %v, want *ResponseTooLargeError with limit %d", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestReverse(t *testing.T) {
+	tests := []struct {
+		name string
+		temp []float64
+		want []float64
+	}{
+		{"empty", nil, nil},
+		{"single", []float64{1}, []float64{1}},
+		{"several", []float64{1, 2, 3}, []float64{3, 2, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var f Forecast
+			for _, temp := range tt.temp {
+				f = append(f, Weather{Temperature: temp})
+			}
+			got := f.Reverse()
+			if len(got) != len(tt.want) {
+				t.Fatalf("Reverse() = %v, want temperatures %v", got, tt.want)
+			}
+			for i, temp := range tt.want {
+				if got[i].Temperature != temp {
+					t.Errorf("Reverse()[%d].Temperature = %v, want %v", i, got[i].Temperature, temp)
+				}
+			}
+			if len(f) > 1 && f[0].Temperature != tt.temp[0] {
+				t.Error("Reverse() changed the original forecast")
+			}
+		})
+	}
+}
+
+func TestPartOfDay(t *testing.T) {
+	c, done := newTestClient(respond(`{"list":[` +
+		`{"dt":100,"main":{"temp":1},"sys":{"pod":"d"}},` +
+		`{"dt":200,"main":{"temp":1},"sys":{"pod":"n"}}]}`))
+	defer done()
+
+	f, err := c.GetForecast(context.Background(), "12345")
+	if err != nil {
+		t.Fatalf("GetForecast() error = %v", err)
+	}
+	if len(f) != 2 || f[0].PartOfDay != "d" || f[1].PartOfDay != "n" {
+		t.Fatalf("GetForecast() = %v, want parts of day d and n", f)
+	}
+
+	noon := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		weather Weather
+		want    bool
+	}{
+		{"day part", Weather{Date: noon.Add(-12 * time.Hour), PartOfDay: "d"}, true},
+		{"night part", Weather{Date: noon, PartOfDay: "n"}, false},
+		{"after sunrise", Weather{Date: noon, Sunrise: noon.Add(-time.Hour), Sunset: noon.Add(time.Hour)}, true},
+		{"after sunset", Weather{Date: noon, Sunrise: noon.Add(-2 * time.Hour), Sunset: noon.Add(-time.Hour)}, false},
+		{"no data at noon", Weather{Date: noon}, true},
+		{"no data at night", Weather{Date: noon.Add(10 * time.Hour)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.weather.IsDaytime(); got != tt.want {
+				t.Errorf("IsDaytime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDailyTempRanges(t *testing.T) {
+	day := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	f := Forecast{
+		{Date: day.Add(3 * time.Hour), TemperatureMin: 8, TemperatureMax: 12},
+		{Date: day.Add(12 * time.Hour), TemperatureMin: 18, TemperatureMax: 22},
+		{Date: day.Add(21 * time.Hour), TemperatureMin: 14, TemperatureMax: 15},
+		{Date: day.Add(30 * time.Hour), TemperatureMin: 17, TemperatureMax: 18},
+	}
+
+	got := f.DailyTempRanges()
+	want := map[string]float64{"20200601": 14, "20200602": 1}
+	if len(got) != len(want) {
+		t.Fatalf("DailyTempRanges() = %v, want %v", got, want)
+	}
+	for day, spread := range want {
+		if got[day] != spread {
+			t.Errorf("DailyTempRanges()[%q] = %v, want %v", day, got[day], spread)
+		}
+	}
+	if got := (Forecast{}).DailyTempRanges(); len(got) != 0 {
+		t.Errorf("DailyTempRanges() of an empty forecast = %v, want empty", got)
+	}
+}
+
+func TestVaporPressureDeficit(t *testing.T) {
+	tests := []struct {
+		name    string
+		weather Weather
+		want    float64
+	}{
+		{"saturated", Weather{Temperature: 25, Humidity: 100}, 0},
+		{"freezing and dry", Weather{Temperature: 0, Humidity: 0}, 0.6108},
+		{"warm", Weather{Temperature: 25, Humidity: 50}, 1.584},
+		{"hot and dry", Weather{Temperature: 35, Humidity: 20}, 4.498},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.weather.VaporPressureDeficit(); math.Abs(got-tt.want) > 0.001 {
+				t.Errorf("VaporPressureDeficit() = %.4f, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWith(t *testing.T) {
+	var units []string
+	c, done := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		units = append(units, r.URL.Query().Get("units"))
+		w.Write([]byte(`{"main":{"temp":1}}`))
+	}, WithUnits(Metric), WithMetrics())
+	defer done()
+	derived := c.With(WithUnits(Imperial))
+
+	for _, client := range []Client{c, derived} {
+		if _, err := client.GetCurrentWeather(context.Background(), "12345"); err != nil {
+			t.Fatalf("GetCurrentWeather() error = %v", err)
+		}
+	}
+	if want := []string{"metric", "imperial"}; strings.Join(units, " ") != strings.Join(want, " ") {
+		t.Errorf("units = %v, want %v", units, want)
+	}
+	for name, client := range map[string]Client{"original": c, "derived": derived} {
+		if text := client.MetricsText(); !strings.Contains(text, "weather_requests_total 2\n") {
+			t.Errorf("%s client doesn't share metrics:\n%s", name, text)
+		}
+	}
+}
+
+func TestNormalizeUnits(t *testing.T) {
+	f := Forecast{
+		{Temperature: 0, Units: Metric},
+		{Temperature: 212, Units: Imperial},
+		{Temperature: 273.15, Units: Kelvin},
+		{Temperature: 5},
+	}
+
+	got := f.NormalizeUnits(Metric)
+	want := []float64{0, 100, 0, 5}
+	for i, w := range got {
+		if math.Abs(w.Temperature-want[i]) > 1e-9 {
+			t.Errorf("entry %d: temperature = %v, want %v", i, w.Temperature, want[i])
+		}
+	}
+	for i, w := range got[:3] {
+		if w.Units != Metric {
+			t.Errorf("entry %d: units = %q, want %q", i, w.Units, Metric)
+		}
+	}
+	if f[1].Temperature != 212 {
+		t.Error("NormalizeUnits() changed the original forecast")
+	}
+}
+
+func TestTransportOptions(t *testing.T) {
+	c := NewClient()
+	if !c.transport.ForceAttemptHTTP2 || c.transport.IdleConnTimeout != 90*time.Second {
+		t.Errorf("default transport: ForceAttemptHTTP2 = %v, IdleConnTimeout = %v, want true, 90s",
+			c.transport.ForceAttemptHTTP2, c.transport.IdleConnTimeout)
+	}
+
+	derived := c.With(WithForceAttemptHTTP2(false), WithIdleConnTimeout(time.Second))
+	if derived.transport.ForceAttemptHTTP2 || derived.transport.IdleConnTimeout != time.Second {
+		t.Errorf("derived transport: ForceAttemptHTTP2 = %v, IdleConnTimeout = %v, want false, 1s",
+			derived.transport.ForceAttemptHTTP2, derived.transport.IdleConnTimeout)
+	}
+	if derived.httpClient.Transport != derived.transport {
+		t.Error("derived HTTP client doesn't use the derived transport")
+	}
+	if !c.transport.ForceAttemptHTTP2 || c.transport.IdleConnTimeout != 90*time.Second {
+		t.Error("options on a derived client changed the original transport")
+	}
+}
+
+// BenchmarkBatchConnectionReuse reports the connections opened per batch as
+// conns/op. Without keep-alives every request in a batch opens one. With them,
+// only the requests that find no idle connection do, and the transport keeps
+// just two idle connections per host unless MaxIdleConnsPerHost is raised.
+func BenchmarkBatchConnectionReuse(b *testing.B) {
+	zips := []string{"1", "2", "3", "4", "5", "6", "7", "8"}
+	for _, bb := range []struct {
+		name       string
+		keepAlives bool
+		maxIdle    int
+	}{
+		{"no keep-alives", false, 0},
+		{"keep-alives", true, 0},
+		{"keep-alives with batch-sized idle pool", true, len(zips)},
+	} {
+		b.Run(bb.name, func(b *testing.B) {
+			var conns int64
+			srv := httptest.NewUnstartedServer(http.HandlerFunc(zipHandler))
+			srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+				if state == http.StateNew {
+					atomic.AddInt64(&conns, 1)
+				}
+			}
+			srv.Start()
+			defer srv.Close()
+
+			c := NewClient(WithAPIKey("test-key"), WithServer(srv.URL+"/", "2.5"))
+			c.transport.DisableKeepAlives = !bb.keepAlives
+			c.transport.MaxIdleConnsPerHost = bb.maxIdle
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := c.GetCurrentWeatherBatchFailFast(context.Background(), zips); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.StopTimer()
+			b.ReportMetric(float64(atomic.LoadInt64(&conns))/float64(b.N), "conns/op")
+		})
+	}
+}
+
+func TestEmoji(t *testing.T) {
+	noon := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	midnight := noon.Add(12 * time.Hour)
+
+	tests := []struct {
+		id   int
+		date time.Time
+		want string
+	}{
+		{211, noon, "⛈️"},
+		{301, noon, "🌦️"},
+		{501, noon, "🌧️"},
+		{601, noon, "❄️"},
+		{741, noon, "🌫️"},
+		{781, noon, "🌪️"},
+		{800, noon, "☀️"},
+		{800, midnight, "🌙"},
+		{801, noon, "🌤️"},
+		{802, noon, "⛅"},
+		{804, noon, "☁️"},
+		{801, midnight, "☁️"},
+		{0, noon, ""},
+	}
+	for _, tt := range tests {
+		if got := (Weather{ConditionID: tt.id, Date: tt.date}).Emoji(); got != tt.want {
+			t.Errorf("Emoji() for %d at %v = %q, want %q", tt.id, tt.date.Format("15:04"), got, tt.want)
+		}
+	}
+}
+
+func TestCoverage(t *testing.T) {
+	at := func(s int64) Weather { return Weather{Date: time.Unix(s, 0)} }
+
+	tests := []struct {
+		name      string
+		f         Forecast
+		wantStart int64
+		wantEnd   int64
+		wantOK    bool
+	}{
+		{"empty", nil, 0, 0, false},
+		{"single", Forecast{at(100)}, 100, 100, true},
+		{"sorted", Forecast{at(100), at(200), at(300)}, 100, 300, true},
+		{"unsorted", Forecast{at(200), at(300), at(100)}, 100, 300, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, ok := tt.f.Coverage()
+			if ok != tt.wantOK {
+				t.Fatalf("Coverage() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				if !start.IsZero() || !end.IsZero() {
+					t.Errorf("Coverage() = %v, %v, want zero times", start, end)
+				}
+				return
+			}
+			if start.Unix() != tt.wantStart || end.Unix() != tt.wantEnd {
+				t.Errorf("Coverage() = %d, %d, want %d, %d", start.Unix(), end.Unix(), tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestContextWithAPIKey(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{"client key", context.Background(), "test-key"},
+		{"context key", ContextWithAPIKey(context.Background(), "ctx-key"), "ctx-key"},
+		{"empty context key", ContextWithAPIKey(context.Background(), ""), "test-key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			c, done := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+				got = r.URL.Query().Get("APPID")
+				w.Write([]byte(`{"main":{"temp":1}}`))
+			})
+			defer done()
+
+			if _, err := c.GetCurrentWeather(tt.ctx, "12345"); err != nil {
+				t.Fatalf("GetCurrentWeather() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("APPID = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	srv := httptest.NewServer(respond(`{"main":{"temp":1}}`))
+	defer srv.Close()
+	c := NewClient(WithServer(srv.URL, "2.5"))
+	if _, err := c.GetCurrentWeather(ContextWithAPIKey(context.Background(), "ctx-key"), "12345"); err != nil {
+		t.Errorf("GetCurrentWeather() with only a context key: error = %v", err)
+	}
+}
+
+func TestMostCommonCondition(t *testing.T) {
+	conds := func(names ...string) Forecast {
+		var f Forecast
+		for _, name := range names {
+			f = append(f, Weather{Condition: name})
+		}
+		return f
+	}
+
+	tests := []struct {
+		name string
+		f    Forecast
+		want string
+	}{
+		{"empty", nil, ""},
+		{"majority", conds("Clear", "Rain", "Clear"), "Clear"},
+		{"tie goes to severe", conds("Clear", "Rain", "Clouds", "Rain", "Clear"), "Rain"},
+		{"tie at equal severity", conds("Mist", "Haze", "Fog"), "Fog"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.f.MostCommonCondition(); got != tt.want {
+				t.Errorf("MostCommonCondition() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindCities(t *testing.T) {
+	const body = `{"list":[` +
+		`{"id":2643743,"name":"London","sys":{"country":"GB"},"main":{"temp":10}},` +
+		`{"id":4298960,"name":"London","sys":{"country":"US"},"main":{"temp":20}}]}`
+
+	tests := []struct {
+		name    string
+		limit   int
+		wantErr bool
+	}{
+		{"zero limit", 0, true},
+		{"lowest limit", 1, false},
+		{"highest limit", 50, false},
+		{"over limit", 51, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var path, query string
+			c, done := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+				path = r.URL.Path
+				query = r.URL.Query().Get("q") + " " + r.URL.Query().Get("cnt")
+				w.Write([]byte(body))
+			})
+			defer done()
+
+			cities, err := c.FindCities(context.Background(), "London", tt.limit)
+			if tt.wantErr {
+				if err == nil || path != "" {
+					t.Errorf("FindCities() error = %v after requesting %q, want an error without a request", err, path)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FindCities() error = %v", err)
+			}
+			if path != "/data/2.5/find" || query != fmt.Sprintf("London %d", tt.limit) {
+				t.Errorf("requested %s with q and cnt %q", path, query)
+			}
+			if len(cities) != 2 {
+				t.Fatalf("got %d cities, want 2", len(cities))
+			}
+			want := CityWeather{City: City{ID: 4298960, Name: "London", Country: "US"}}
+			if cities[1].City != want.City || cities[1].Temperature != 20 {
+				t.Errorf("cities[1] = %+v, want %+v at 20°", cities[1].City, want.City)
+			}
+		})
+	}
+}
+
+func TestWeekendOnly(t *testing.T) {
+	// 2020-06-05 is a Friday.
+	friday := time.Date(2020, 6, 5, 12, 0, 0, 0, time.UTC)
+	var f Forecast
+	for i := 0; i < 4; i++ {
+		f = append(f, Weather{Date: friday.AddDate(0, 0, i)})
+	}
+
+	got := f.WeekendOnly()
+	if len(got) != 2 || got[0].Date.Weekday() != time.Saturday || got[1].Date.Weekday() != time.Sunday {
+		t.Errorf("WeekendOnly() = %v, want Saturday and Sunday", got)
+	}
+
+	// Late Friday in UTC is already Saturday in Tokyo, so the same instant is
+	// on the weekend only when the first entry is in Tokyo.
+	tokyo := time.FixedZone("JST", 9*60*60)
+	late := friday.Add(11 * time.Hour)
+	mixed := Forecast{{Date: friday.In(tokyo)}, {Date: late}}
+	if got := mixed.WeekendOnly(); len(got) != 1 || !got[0].Date.Equal(late) {
+		t.Errorf("WeekendOnly() = %v, want the late entry, on Saturday in Tokyo", got)
+	}
+	mixed = Forecast{{Date: friday}, {Date: late.In(tokyo)}}
+	if got := mixed.WeekendOnly(); len(got) != 0 {
+		t.Errorf("WeekendOnly() = %v, want no entries, as both are on Friday in UTC", got)
+	}
+	if got := (Forecast{}).WeekendOnly(); len(got) != 0 {
+		t.Errorf("WeekendOnly() = %v, want no entries", got)
+	}
+}
+
+func TestIsSevere(t *testing.T) {
+	tests := []struct {
+		name    string
+		weather Weather
+		want    bool
+	}{
+		{"thunderstorm", Weather{ConditionID: 211}, true},
+		{"extreme rain", Weather{ConditionID: 504}, true},
+		{"squall", Weather{ConditionID: 771}, true},
+		{"tornado", Weather{ConditionID: 781}, true},
+		{"light rain", Weather{ConditionID: 500}, false},
+		{"clear", Weather{ConditionID: 800}, false},
+		{"gale", Weather{ConditionID: 800, WindSpeed: 17.2, Units: Metric}, true},
+		{"gale in mph", Weather{ConditionID: 800, WindSpeed: 40, Units: Imperial}, true},
+		{"breezy", Weather{ConditionID: 800, WindSpeed: 10, Units: Metric}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.weather.IsSevere(); got != tt.want {
+				t.Errorf("IsSevere() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInterpolateMissing(t *testing.T) {
+	at := func(hour int, humidity, pressure float64) Weather {
+		return Weather{Date: time.Unix(0, 0).Add(time.Duration(hour) * time.Hour), Humidity: humidity, Pressure: pressure}
+	}
+
+	tests := []struct {
+		name         string
+		f            Forecast
+		wantHumidity []float64
+		wantPressure []float64
+	}{
+		{"nothing missing", Forecast{at(0, 50, 1000), at(3, 60, 1010)}, []float64{50, 60}, []float64{1000, 1010}},
+		{"gap", Forecast{at(0, 50, 1000), at(3, 0, 0), at(6, 70, 1020)}, []float64{50, 60, 70}, []float64{1000, 1010, 1020}},
+		{"uneven spacing", Forecast{at(0, 40, 1000), at(1, 0, 1000), at(4, 80, 1000)}, []float64{40, 50, 80}, []float64{1000, 1000, 1000}},
+		{"leading and trailing", Forecast{at(0, 0, 1000), at(3, 50, 0), at(6, 0, 1010)}, []float64{0, 50, 0}, []float64{1000, 1005, 1010}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := append(Forecast(nil), tt.f...)
+			got := tt.f.InterpolateMissing()
+			for i, w := range got {
+				if math.Abs(w.Humidity-tt.wantHumidity[i]) > 1e-9 || math.Abs(w.Pressure-tt.wantPressure[i]) > 1e-9 {
+					t.Errorf("entry %d: humidity %v, pressure %v, want %v, %v",
+						i, w.Humidity, w.Pressure, tt.wantHumidity[i], tt.wantPressure[i])
+				}
+			}
+			for i := range original {
+				if original[i] != tt.f[i] {
+					t.Errorf("InterpolateMissing() changed entry %d of the original forecast", i)
+				}
+			}
+		})
+	}
+}
+
+func TestFlexInt(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantID  int
+		wantErr bool
+	}{
+		{"integer", `{"id":42,"weather":[{"id":500}],"main":{"temp":1}}`, 500, false},
+		{"fractional", `{"id":42.0,"weather":[{"id":500.0}],"main":{"temp":1}}`, 500, false},
+		{"string", `{"weather":[{"id":"500"}],"main":{"temp":1}}`, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, done := newTestClient(respond(tt.body))
+			defer done()
+
+			w, err := c.GetCurrentWeather(context.Background(), "12345")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetCurrentWeather() error = %v, want error %v", err, tt.wantErr)
+			}
+			if w.ConditionID != tt.wantID {
+				t.Errorf("ConditionID = %d, want %d", w.ConditionID, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestSunTimesByDay(t *testing.T) {
+	day := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	rise, set := day.Add(5*time.Hour), day.Add(21*time.Hour)
+	f := Forecast{
+		{Date: day.Add(3 * time.Hour)},
+		{Date: day.Add(6 * time.Hour), Sunrise: rise, Sunset: set},
+		{Date: day.Add(9 * time.Hour), Sunrise: rise.Add(time.Minute), Sunset: set},
+		{Date: day.Add(27 * time.Hour)},
+	}
+
+	got := f.SunTimesByDay()
+	if len(got) != 1 {
+		t.Fatalf("SunTimesByDay() = %v, want one day", got)
+	}
+	if times := got["20200601"]; !times[0].Equal(rise) || !times[1].Equal(set) {
+		t.Errorf("SunTimesByDay()[\"20200601\"] = %v, want %v and %v", times, rise, set)
+	}
+}
+
+func TestApparentHottestDay(t *testing.T) {
+	day := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		f      Forecast
+		want   string
+		wantOK bool
+	}{
+		{"empty", nil, "", false},
+		{"feels hotter than it is", Forecast{
+			{Date: day, Temperature: 30, FeelsLike: 28},
+			{Date: day.AddDate(0, 0, 1), Temperature: 27, FeelsLike: 33},
+		}, "20200602", true},
+		{"tie goes to earlier day", Forecast{
+			{Date: day, FeelsLike: 25},
+			{Date: day.AddDate(0, 0, 1), FeelsLike: 25},
+		}, "20200601", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.f.ApparentHottestDay()
+			if ok != tt.wantOK {
+				t.Fatalf("ApparentHottestDay() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && got.Date.Format("20060102") != tt.want {
+				t.Errorf("ApparentHottestDay() = %v, want %s", got.Date, tt.want)
+			}
+		})
+	}
+}
+
+func TestClampTemperatures(t *testing.T) {
+	f := Forecast{
+		{Temperature: -80, TemperatureMin: -90, TemperatureMax: -70, FeelsLike: -100},
+		{Temperature: 20, TemperatureMin: 15, TemperatureMax: 25, FeelsLike: 19},
+		{Temperature: 70, TemperatureMin: 65, TemperatureMax: 75, FeelsLike: 80},
+	}
+
+	got := f.ClampTemperatures(-50, 60)
+	want := []Weather{
+		{Temperature: -50, TemperatureMin: -50, TemperatureMax: -50, FeelsLike: -50},
+		{Temperature: 20, TemperatureMin: 15, TemperatureMax: 25, FeelsLike: 19},
+		{Temperature: 60, TemperatureMin: 60, TemperatureMax: 60, FeelsLike: 60},
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if f[0].Temperature != -80 {
+		t.Error("ClampTemperatures() changed the original forecast")
+	}
+}
+
+func TestAPIKeyErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want error
+	}{
+		// The messages are pinned from https://openweathermap.org/faq#error401.
+		{"invalid", `{"cod":401, "message": "Invalid API key. Please see https://openweathermap.org/faq#error401 for more info."}`, ErrInvalidAPIKey},
+		{"not activated", `{"cod":401, "message": "Your API key is not activated yet. Within the next couple of hours, it will be activated and ready to use."}`, ErrInactiveAPIKey},
+		{"other message mentioning activation", `{"cod":401, "message": "Key inactive, not activated"}`, ErrInvalidAPIKey},
+		{"plain text", `Unauthorized`, ErrInvalidAPIKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, done := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(tt.body))
+			})
+			defer done()
+
+			_, err := c.GetCurrentWeather(context.Background(), "12345")
+			if !errors.Is(err, tt.want) {
+				t.Errorf("GetCurrentWeather() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestWindSpeedIn(t *testing.T) {
+	tests := []struct {
+		name    string
+		weather Weather
+		unit    string
+		want    float64
+	}{
+		{"metric to m/s", Weather{WindSpeed: 10, Units: Metric}, "ms", 10},
+		{"metric to km/h", Weather{WindSpeed: 10, Units: Metric}, "kmh", 36},
+		{"metric to mph", Weather{WindSpeed: 10, Units: Metric}, "mph", 22.369},
+		{"metric to knots", Weather{WindSpeed: 10, Units: Metric}, "knots", 19.438},
+		{"kelvin is m/s", Weather{WindSpeed: 10, Units: Kelvin}, "ms", 10},
+		{"imperial to mph", Weather{WindSpeed: 10, Units: Imperial}, "mph", 10},
+		{"imperial to m/s", Weather{WindSpeed: 10, Units: Imperial}, "ms", 4.4704},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.weather.WindSpeedIn(tt.unit); math.Abs(got-tt.want) > 0.001 {
+				t.Errorf("WindSpeedIn(%q) = %v, want %v", tt.unit, got, tt.want)
+			}
+		})
+	}
+	if got := (Weather{WindSpeed: 10}).WindSpeedIn("furlongs"); !math.IsNaN(got) {
+		t.Errorf("WindSpeedIn of an unknown unit = %v, want NaN", got)
+	}
+}
+
+func TestGroupByWeek(t *testing.T) {
+	// 2020-12-31 is a Thursday in ISO week 53 of 2020.
+	thursday := time.Date(2020, 12, 31, 12, 0, 0, 0, time.UTC)
+	f := Forecast{
+		{Date: thursday},
+		{Date: thursday.AddDate(0, 0, 3)},
+		{Date: thursday.AddDate(0, 0, 4)},
+		{Date: thursday.AddDate(0, 0, 11)},
+	}
+
+	got := f.GroupByWeek()
+	want := map[string]int{"2020-W53": 2, "2021-W01": 1, "2021-W02": 1}
+	if len(got) != len(want) {
+		t.Fatalf("GroupByWeek() has weeks %v, want %v", got, want)
+	}
+	for week, n := range want {
+		if len(got[week]) != n {
+			t.Errorf("week %s has %d entries, want %d", week, len(got[week]), n)
+		}
+	}
+	if got := (Forecast{}).GroupByWeek(); len(got) != 0 {
+		t.Errorf("GroupByWeek() of an empty forecast = %v, want empty", got)
+	}
+}
+
+func TestDaylightDurationAndSolarNoon(t *testing.T) {
+	rise := time.Date(2020, 6, 1, 5, 0, 0, 0, time.UTC)
+	set := rise.Add(15 * time.Hour)
+
+	tests := []struct {
+		name         string
+		weather      Weather
+		wantDuration time.Duration
+		wantNoon     time.Time
+	}{
+		{"both set", Weather{Sunrise: rise, Sunset: set}, 15 * time.Hour, rise.Add(450 * time.Minute)},
+		{"no sunset", Weather{Sunrise: rise}, 0, time.Time{}},
+		{"no sunrise", Weather{Sunset: set}, 0, time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.weather.DaylightDuration(); got != tt.wantDuration {
+				t.Errorf("DaylightDuration() = %v, want %v", got, tt.wantDuration)
+			}
+			if got := tt.weather.SolarNoon(); !got.Equal(tt.wantNoon) {
+				t.Errorf("SolarNoon() = %v, want %v", got, tt.wantNoon)
+			}
+		})
+	}
+}
+
+func TestSolarEnergyEstimate(t *testing.T) {
+	day := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	entry := func(clouds float64) Weather {
+		return Weather{Date: day.Add(12 * time.Hour), Sunrise: day.Add(6 * time.Hour), Sunset: day.Add(18 * time.Hour), Clouds: clouds}
+	}
+
+	tests := []struct {
+		name    string
+		f       Forecast
+		panelKW float64
+		want    float64
+	}{
+		{"empty", nil, 5, 0},
+		{"clear", Forecast{entry(0)}, 5, 5 * 12 * 2 / math.Pi},
+		{"half cloudy", Forecast{entry(50)}, 5, 5 * 6 * 2 / math.Pi},
+		{"no panels", Forecast{entry(0)}, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.f.SolarEnergyEstimate(tt.panelKW); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("SolarEnergyEstimate(%v) = %v, want %v", tt.panelKW, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSmoothHumidity(t *testing.T) {
+	humidity := []float64{10, 20, 60, 20, 10}
+	var f Forecast
+	for _, h := range humidity {
+		f = append(f, Weather{Humidity: h, Temperature: 1})
+	}
+
+	tests := []struct {
+		name   string
+		window int
+		want   []float64
+	}{
+		{"no smoothing", 1, []float64{10, 20, 60, 20, 10}},
+		{"window 2", 2, []float64{15, 40, 40, 15, 10}},
+		{"window 3", 3, []float64{15, 30, 100.0 / 3, 30, 15}},
+		{"wider than the forecast", 11, []float64{24, 24, 24, 24, 24}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := f.SmoothHumidity(tt.window)
+			for i, w := range got {
+				if math.Abs(w.Humidity-tt.want[i]) > 1e-9 || w.Temperature != 1 {
+					t.Errorf("entry %d = %+v, want humidity %v", i, w, tt.want[i])
+				}
+			}
+			if f[2].Humidity != 60 {
+				t.Error("SmoothHumidity() changed the original forecast")
+			}
+		})
+	}
+}
+
+func TestGetCurrentWeatherTimeout(t *testing.T) {
+	c, done := newTestClient(zipHandler)
+	defer done()
+
+	tests := []struct {
+		name    string
+		zip     string
+		wantErr error
+	}{
+		{"in time", "1", nil},
+		{"too slow", "slow", context.DeadlineExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := c.GetCurrentWeatherTimeout(context.Background(), tt.zip, 50*time.Millisecond)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("GetCurrentWeatherTimeout() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTomorrow(t *testing.T) {
+	day := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	f := Forecast{
+		{Date: day, Temperature: 10},
+		{Date: day.AddDate(0, 0, 1), Temperature: 20},
+		{Date: day.AddDate(0, 0, 1).Add(3 * time.Hour), Temperature: 30},
+	}
+
+	tests := []struct {
+		name     string
+		f        Forecast
+		now      time.Time
+		wantTemp float64
+		wantOK   bool
+	}{
+		{"covered", f, day, 25, true},
+		{"late in another zone", f, time.Date(2020, 5, 31, 22, 0, 0, 0, time.FixedZone("EDT", -4*60*60)), 25, true},
+		{"not covered", f, day.AddDate(0, 0, 1), 0, false},
+		{"empty", nil, day, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.f.Tomorrow(tt.now)
+			if ok != tt.wantOK {
+				t.Fatalf("Tomorrow() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && got.Temperature != tt.wantTemp {
+				t.Errorf("Tomorrow().Temperature = %v, want %v", got.Temperature, tt.wantTemp)
+			}
+		})
+	}
+}
+
+func TestComfortableHours(t *testing.T) {
+	var f Forecast
+	for i, temp := range []float64{15, 20, 22, 25, 30} {
+		humidity := 50.0
+		if i == 2 {
+			humidity = 80
+		}
+		f = append(f, Weather{Date: time.Unix(0, 0).Add(time.Duration(i) * 3 * time.Hour), Temperature: temp, Humidity: humidity})
+	}
+
+	tests := []struct {
+		name        string
+		min, max    float64
+		maxHumidity float64
+		want        time.Duration
+	}{
+		{"inclusive bounds", 20, 25, 60, 6 * time.Hour},
+		{"humid entries allowed", 20, 25, 100, 9 * time.Hour},
+		{"nothing comfortable", 40, 50, 100, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := f.ComfortableHours(tt.min, tt.max, tt.maxHumidity); got != tt.want {
+				t.Errorf("ComfortableHours() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPressureAccessors(t *testing.T) {
+	tests := []struct {
+		pressure float64
+		wantInHg float64
+	}{
+		{1013.25, 29.92},
+		{1000, 29.53},
+		{0, 0},
+	}
+	for _, tt := range tests {
+		w := Weather{Pressure: tt.pressure}
+		if got := w.PressureHpa(); got != tt.pressure {
+			t.Errorf("PressureHpa() = %v, want %v", got, tt.pressure)
+		}
+		if got := w.PressureInHg(); math.Abs(got-tt.wantInHg) > 0.01 {
+			t.Errorf("PressureInHg() for %v hPa = %v, want %v", tt.pressure, got, tt.wantInHg)
+		}
+	}
+}
+
+func TestWithDialer(t *testing.T) {
+	var dials int32
+	dialer := &net.Dialer{
+		Control: func(network, address string, c syscall.RawConn) error {
+			atomic.AddInt32(&dials, 1)
+			return nil
+		},
+	}
+	c, done := newTestClient(respond(`{"main":{"temp":1}}`), WithDialer(dialer))
+	defer done()
+
+	if _, err := c.GetCurrentWeather(context.Background(), "12345"); err != nil {
+		t.Fatalf("GetCurrentWeather() error = %v", err)
+	}
+	if atomic.LoadInt32(&dials) == 0 {
+		t.Error("the client didn't connect with the dialer")
+	}
+	if NewClient().transport.DialContext == nil {
+		t.Error("the default transport has no dialer")
+	}
+}
+
+func TestDailyIntegral(t *testing.T) {
+	day := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	at := func(hour int, temp float64) Weather {
+		return Weather{Date: day.Add(time.Duration(hour) * time.Hour), Temperature: temp, TemperatureMax: temp}
+	}
+
+	tests := []struct {
+		name string
+		f    Forecast
+		want float64
+	}{
+		{"single sample", Forecast{at(12, 20)}, 20},
+		{"even spacing", Forecast{at(0, 10), at(6, 20), at(12, 30)}, 20},
+		// A plain average would give 20; the 10° reading spans the most time.
+		{"uneven spacing", Forecast{at(0, 10), at(10, 10), at(11, 40)}, 11.36},
+		{"unsorted", Forecast{at(12, 30), at(0, 10), at(6, 20)}, 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.f.DailyIntegral()
+			if len(got) != 1 {
+				t.Fatalf("DailyIntegral() = %v, want one day", got)
+			}
+			if math.Abs(got[0].Temperature-tt.want) > 0.01 {
+				t.Errorf("Temperature = %v, want %v", got[0].Temperature, tt.want)
+			}
+			if want := tt.f.MaximumTemperature(); got[0].TemperatureMax != want {
+				t.Errorf("TemperatureMax = %v, want it aggregated as by Daily (%v)", got[0].TemperatureMax, want)
+			}
+		})
+	}
+}
+
+func TestDetectFrontPassage(t *testing.T) {
+	at := func(hour int, pressure, wind float64) Weather {
+		return Weather{Date: time.Unix(0, 0).Add(time.Duration(hour) * time.Hour), Pressure: pressure, WindDirection: wind}
+	}
+
+	tests := []struct {
+		name string
+		f    Forecast
+		want []int
+	}{
+		{"empty", nil, nil},
+		{"front", Forecast{at(0, 1010, 180), at(3, 1006, 270)}, []int{3}},
+		{"drop without shift", Forecast{at(0, 1010, 180), at(3, 1006, 200)}, nil},
+		{"shift without drop", Forecast{at(0, 1010, 180), at(3, 1009, 270)}, nil},
+		{"shift across north", Forecast{at(0, 1010, 340), at(3, 1006, 30)}, []int{3}},
+		{"drop scaled by gap", Forecast{at(0, 1010, 180), at(6, 1006, 270)}, nil},
+		{"quick drop", Forecast{at(0, 1010, 180), at(1, 1009, 270)}, []int{1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.f.DetectFrontPassage()
+			if len(got) != len(tt.want) {
+				t.Fatalf("DetectFrontPassage() = %v, want hours %v", got, tt.want)
+			}
+			for i, hour := range tt.want {
+				if want := time.Unix(0, 0).Add(time.Duration(hour) * time.Hour); !got[i].Equal(want) {
+					t.Errorf("DetectFrontPassage()[%d] = %v, want %v", i, got[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestRoundTemperatures(t *testing.T) {
+	w := Weather{Temperature: 21.37, TemperatureMin: 18.74, TemperatureMax: 24.5, FeelsLike: -3.26, Humidity: 55.5}
+
+	tests := []struct {
+		name string
+		step float64
+		want Weather
+	}{
+		{"whole degrees", 1, Weather{Temperature: 21, TemperatureMin: 19, TemperatureMax: 25, FeelsLike: -3, Humidity: 55.5}},
+		{"half degrees", 0.5, Weather{Temperature: 21.5, TemperatureMin: 18.5, TemperatureMax: 24.5, FeelsLike: -3.5, Humidity: 55.5}},
+		{"fives", 5, Weather{Temperature: 20, TemperatureMin: 20, TemperatureMax: 25, FeelsLike: -5, Humidity: 55.5}},
+		{"zero step", 0, w},
+		{"negative step", -1, w},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := w.RoundTemperatures(tt.step); got != tt.want {
+				t.Errorf("RoundTemperatures(%v) = %+v, want %+v", tt.step, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtremes(t *testing.T) {
+	at := func(s int64) time.Time { return time.Unix(s, 0) }
+	f := Forecast{
+		{Date: at(1), TemperatureMin: 5, TemperatureMax: 10, WindSpeed: 3, Humidity: 40, PrecipitationProbability: 0.1},
+		{Date: at(2), TemperatureMin: 2, TemperatureMax: 15, WindSpeed: 9, Humidity: 90, PrecipitationProbability: 0.6},
+		{Date: at(3), TemperatureMin: 4, TemperatureMax: 20, WindSpeed: 9, Humidity: 30, PrecipitationProbability: 0.3},
+	}
+
+	got := f.Extremes()
+	want := Extremes{
+		MinTemp: 2, MinTempTime: at(2),
+		MaxTemp: 20, MaxTempTime: at(3),
+		MaxWind: 9, MaxWindTime: at(2),
+		MinHumidity: 30, MaxHumidity: 90,
+		MaxPrecipitationProbability: 0.6,
+	}
+	if got != want {
+		t.Errorf("Extremes() = %+v, want %+v", got, want)
+	}
+	if got.MinTemp != f.MinimumTemperature() || got.MaxTemp != f.MaximumTemperature() {
+		t.Error("Extremes() doesn't match MinimumTemperature and MaximumTemperature")
+	}
+
+	empty := Forecast{}.Extremes()
+	if !math.IsInf(empty.MinTemp, 1) || !math.IsInf(empty.MaxTemp, -1) {
+		t.Errorf("Extremes() of an empty forecast = %+v, want infinite temperatures", empty)
+	}
+}
+
+func TestWithProxyFromEnvironment(t *testing.T) {
+	c := NewClient()
+	c.transport.Proxy = nil
+	derived := c.With(WithProxyFromEnvironment())
+
+	if c.transport.Proxy != nil {
+		t.Error("WithProxyFromEnvironment() changed the original transport")
+	}
+	if derived.httpClient.Transport != derived.transport {
+		t.Error("the HTTP client doesn't use the configured transport")
+	}
+
+	// http.ProxyFromEnvironment reads the environment only once per process,
+	// so the request is made by a copy of the test binary started with
+	// HTTP_PROXY set. The server name isn't local, as ProxyFromEnvironment
+	// never proxies requests to localhost.
+	if proxy := os.Getenv("WEATHER_TEST_PROXY"); proxy != "" {
+		derived = derived.With(WithAPIKey("test-key"), WithServer("http://weather.example/", "2.5"))
+		w, err := derived.GetCurrentWeather(context.Background(), "12345")
+		if err != nil {
+			t.Fatalf("GetCurrentWeather() error = %v", err)
+		}
+		if w.Temperature != 20 {
+			t.Errorf("got temperature %v, want 20 from the proxy", w.Temperature)
+		}
+		return
+	}
+
+	var proxied int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "weather.example" || r.URL.Query().Get("zip") != "12345" {
+			http.Error(w, "unexpected request for "+r.URL.String(), http.StatusBadGateway)
+			return
+		}
+		atomic.AddInt32(&proxied, 1)
+		fmt.Fprint(w, `{"main":{"temp":20}}`)
+	}))
+	defer proxy.Close()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestWithProxyFromEnvironment$")
+	cmd.Env = append(os.Environ(), "WEATHER_TEST_PROXY=1", "HTTP_PROXY="+proxy.URL, "http_proxy=", "NO_PROXY=", "no_proxy=")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("request through the proxy failed: %v\n%s", err, out)
+	}
+	if atomic.LoadInt32(&proxied) != 1 {
+		t.Errorf("proxy received %d requests, want 1", proxied)
+	}
+}
+
+func TestTemperatureVolatility(t *testing.T) {
+	temps := func(values ...float64) Forecast {
+		var f Forecast
+		for _, v := range values {
+			f = append(f, Weather{Temperature: v})
+		}
+		return f
+	}
+
+	tests := []struct {
+		name string
+		f    Forecast
+		want float64
+	}{
+		{"empty", nil, 0},
+		{"single", temps(10), 0},
+		{"steady", temps(10, 10, 10), 0},
+		{"up and down", temps(10, 14, 8, 10), 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.f.TemperatureVolatility(); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("TemperatureVolatility() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONWithUnits(t *testing.T) {
+	w := Weather{Date: time.Unix(100, 0).UTC(), Temperature: 21.5, ConditionID: 800, Units: Metric}
+
+	b, err := w.JSONWithUnits()
+	if err != nil {
+		t.Fatalf("JSONWithUnits() error = %v", err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("JSONWithUnits() = %s, not a JSON object: %v", b, err)
+	}
+	if len(got) != len(w.Snapshot()) {
+		t.Errorf("JSONWithUnits() has %d keys, want the %d of Snapshot", len(got), len(w.Snapshot()))
+	}
+
+	tests := []struct {
+		key  string
+		want interface{}
+	}{
+		{"units", "metric"},
+		{"temperature", 21.5},
+		{"condition_id", 800.0},
+		{"date", "1970-01-01T00:01:40Z"},
+	}
+	for _, tt := range tests {
+		if got[tt.key] != tt.want {
+			t.Errorf("JSONWithUnits()[%q] = %#v, want %#v", tt.key, got[tt.key], tt.want)
+		}
+	}
+}
+
+func TestThunderstorms(t *testing.T) {
+	var f Forecast
+	for _, id := range []int{200, 500, 232, 800, 299, 300} {
+		f = append(f, Weather{ConditionID: id})
+	}
+
+	got := f.Thunderstorms()
+	want := []int{200, 232, 299}
+	if len(got) != len(want) {
+		t.Fatalf("Thunderstorms() = %v, want IDs %v", got, want)
+	}
+	for i, id := range want {
+		if got[i].ConditionID != id {
+			t.Errorf("Thunderstorms()[%d].ConditionID = %d, want %d", i, got[i].ConditionID, id)
+		}
+	}
+}
+
+func TestSeverityRank(t *testing.T) {
+	tests := []struct {
+		id   int
+		want int
+	}{
+		{800, 0},
+		{803, 1},
+		{701, 2},
+		{741, 2},
+		{711, 3},
+		{761, 3},
+		{301, 4},
+		{501, 5},
+		{601, 6},
+		{771, 7},
+		{211, 8},
+		{781, 9},
+		{0, 0},
+	}
+	for _, tt := range tests {
+		if got := (Weather{ConditionID: tt.id}).SeverityRank(); got != tt.want {
+			t.Errorf("SeverityRank() for %d = %d, want %d", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestAsOf(t *testing.T) {
+	f := Forecast{{Date: time.Unix(100, 0)}, {Date: time.Unix(200, 0)}, {Date: time.Unix(300, 0)}}
+
+	tests := []struct {
+		name string
+		t    time.Time
+		want int
+	}{
+		{"before all", time.Unix(0, 0), 3},
+		{"at an entry", time.Unix(200, 0), 2},
+		{"between entries", time.Unix(250, 0), 1},
+		{"after all", time.Unix(400, 0), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := f.AsOf(tt.t); len(got) != tt.want {
+				t.Errorf("AsOf() = %v, want %d entries", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateAPIKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		noKey   bool
+		want    bool
+		wantErr bool
+	}{
+		{"valid", http.StatusOK, `{"main":{"temp":1}}`, false, true, false},
+		{"invalid", http.StatusUnauthorized, `{"cod":401,"message":"Invalid API key."}`, false, false, false},
+		{"inactive", http.StatusUnauthorized, `{"cod":401,"message":"API key is not activated yet."}`, false, false, false},
+		{"missing", http.StatusOK, `{"main":{"temp":1}}`, true, false, false},
+		{"server error", http.StatusInternalServerError, `oops`, false, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+			opts := []Option{WithServer(srv.URL, "2.5")}
+			if !tt.noKey {
+				opts = append(opts, WithAPIKey("test-key"))
+			}
+
+			got, err := NewClient(opts...).ValidateAPIKey(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateAPIKey() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ValidateAPIKey() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHourlyForDay(t *testing.T) {
+	day := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	f := Forecast{
+		{Date: day.Add(-3 * time.Hour), Temperature: 1},
+		{Date: day.Add(3 * time.Hour), Temperature: 2},
+		{Date: day.Add(15 * time.Hour), Temperature: 3},
+		{Date: day.Add(27 * time.Hour), Temperature: 4},
+	}
+
+	tests := []struct {
+		name string
+		f    Forecast
+		day  time.Time
+		want []float64
+	}{
+		{"middle day", f, day.Add(12 * time.Hour), []float64{2, 3}},
+		{"day in another zone", f, time.Date(2020, 5, 31, 22, 0, 0, 0, time.FixedZone("EDT", -4*60*60)), []float64{2, 3}},
+		{"uncovered day", f, day.AddDate(0, 0, 5), nil},
+		{"empty", nil, day, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			times, temps := tt.f.HourlyForDay(tt.day)
+			if len(times) != len(tt.want) || len(temps) != len(tt.want) {
+				t.Fatalf("HourlyForDay() = %v, %v, want temperatures %v", times, temps, tt.want)
+			}
+			for i, temp := range tt.want {
+				if temps[i] != temp {
+					t.Errorf("temperature %d = %v, want %v", i, temps[i], temp)
+				}
+			}
+		})
+	}
+}
+
+func TestWithUnitsAlias(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{"no alias", []Option{WithUnits(Metric)}, "metric"},
+		{"alias", []Option{WithUnits(Metric), WithUnitsAlias(Metric, "si")}, "si"},
+		{"alias for other units", []Option{WithUnits(Imperial), WithUnitsAlias(Metric, "si")}, "imperial"},
+		{"kelvin", nil, ""},
+		{"kelvin alias", []Option{WithUnitsAlias(Kelvin, "standard")}, "standard"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			c, done := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+				got = r.URL.Query().Get("units")
+				w.Write([]byte(`{"main":{"temp":1}}`))
+			}, tt.opts...)
+			defer done()
+
+			if _, err := c.GetCurrentWeather(context.Background(), "12345"); err != nil {
+				t.Fatalf("GetCurrentWeather() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("units = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	base := NewClient(WithUnitsAlias(Metric, "si"))
+	base.With(WithUnitsAlias(Imperial, "us"))
+	if _, ok := base.unitsAliases[Imperial]; ok {
+		t.Error("an alias on a derived client changed the original")
+	}
+}
+
+func TestCountByCondition(t *testing.T) {
+	f := Forecast{{Condition: "Rain"}, {Condition: "Clear"}, {Condition: "Rain"}, {}}
+
+	got := f.CountByCondition()
+	want := map[string]int{"Rain": 2, "Clear": 1, "": 1}
+	if len(got) != len(want) {
+		t.Fatalf("CountByCondition() = %v, want %v", got, want)
+	}
+	for cond, n := range want {
+		if got[cond] != n {
+			t.Errorf("CountByCondition()[%q] = %d, want %d", cond, got[cond], n)
+		}
+	}
+	if got := (Forecast{}).CountByCondition(); len(got) != 0 {
+		t.Errorf("CountByCondition() of an empty forecast = %v, want empty", got)
+	}
+}
+
+func TestComfortIndex(t *testing.T) {
+	tests := []struct {
+		name    string
+		weather Weather
+		want    float64
+	}{
+		{"ideal", Weather{Temperature: 22, Humidity: 45, WindSpeed: 2, Units: Metric}, 100},
+		{"too warm", Weather{Temperature: 26, Humidity: 45, Units: Metric}, 90},
+		{"feels colder", Weather{Temperature: 22, FeelsLike: 18, Humidity: 45, Units: Metric}, 90},
+		{"zero feels-like uses temperature", Weather{Temperature: 2, FeelsLike: 0, Humidity: 45, Units: Metric}, 10},
+		{"feels-like just above zero", Weather{Temperature: 2, FeelsLike: 0.1, Humidity: 45, Units: Metric}, 0.5},
+		{"humid", Weather{Temperature: 22, Humidity: 80, Units: Metric}, 80},
+		{"windy", Weather{Temperature: 22, Humidity: 45, WindSpeed: 10, Units: Metric}, 80},
+		{"imperial", Weather{Temperature: 78.8, Humidity: 45, Units: Imperial}, 90},
+		{"unbearable", Weather{Temperature: 45, Humidity: 95, WindSpeed: 30, Units: Metric}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.weather.ComfortIndex(); math.Abs(got-tt.want) > 1e-6 {
+				t.Errorf("ComfortIndex() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSeaAndGroundLevelPressure(t *testing.T) {
+	tests := []struct {
+		name       string
+		main       string
+		wantSea    float64
+		wantGround float64
+	}{
+		{"both reported", `{"temp":1,"pressure":1010,"sea_level":1015,"grnd_level":990}`, 1015, 990},
+		{"fall back to pressure", `{"temp":1,"pressure":1010}`, 1010, 1010},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, done := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasSuffix(r.URL.Path, "/forecast") {
+					fmt.Fprintf(w, `{"list":[{"dt":100,"main":%s}]}`, tt.main)
+					return
+				}
+				fmt.Fprintf(w, `{"main":%s}`, tt.main)
+			})
+			defer done()
+
+			current, err := c.GetCurrentWeather(context.Background(), "12345")
+			if err != nil {
+				t.Fatalf("GetCurrentWeather() error = %v", err)
+			}
+			forecast, err := c.GetForecast(context.Background(), "12345")
+			if err != nil {
+				t.Fatalf("GetForecast() error = %v", err)
+			}
+			for _, w := range []Weather{current, forecast[0]} {
+				if w.SeaLevelPressure != tt.wantSea || w.GroundLevelPressure != tt.wantGround {
+					t.Errorf("sea level %v, ground level %v, want %v, %v",
+						w.SeaLevelPressure, w.GroundLevelPressure, tt.wantSea, tt.wantGround)
+				}
+			}
+		})
+	}
+}
+
+func TestBucketize(t *testing.T) {
+	day := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	var f Forecast
+	for h := 0; h < 24; h += 3 {
+		f = append(f, Weather{Date: day.Add(time.Duration(h) * time.Hour), Temperature: float64(h)})
+	}
+
+	tests := []struct {
+		name      string
+		interval  time.Duration
+		loc       *time.Location
+		wantStart []int
+		wantTemp  []float64
+	}{
+		{"six hours", 6 * time.Hour, time.UTC, []int{0, 6, 12, 18}, []float64{1.5, 7.5, 13.5, 19.5}},
+		{"whole day", 24 * time.Hour, time.UTC, []int{0}, []float64{10.5}},
+		{"aligned to local midnight", 12 * time.Hour, time.FixedZone("UTC+2", 2*60*60), []int{-2, 10}, []float64{4.5, 16.5}},
+		{"zero interval", 0, time.UTC, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := f.Bucketize(tt.interval, tt.loc)
+			if len(got) != len(tt.wantStart) {
+				t.Fatalf("Bucketize() = %v, want %d buckets", got, len(tt.wantStart))
+			}
+			for i, w := range got {
+				if want := day.Add(time.Duration(tt.wantStart[i]) * time.Hour); !w.Date.Equal(want) {
+					t.Errorf("bucket %d starts at %v, want %v", i, w.Date, want)
+				}
+				if w.Temperature != tt.wantTemp[i] {
+					t.Errorf("bucket %d temperature = %v, want %v", i, w.Temperature, tt.wantTemp[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCumulativeRain(t *testing.T) {
+	var f Forecast
+	for i, rain := range []float64{0, 1.5, 0, 2} {
+		f = append(f, Weather{Date: time.Unix(int64(i)*3600, 0), Rain: rain})
+	}
+
+	times, totals := f.CumulativeRain()
+	want := []float64{0, 1.5, 1.5, 3.5}
+	if len(times) != len(want) || len(totals) != len(want) {
+		t.Fatalf("CumulativeRain() = %v, %v, want totals %v", times, totals, want)
+	}
+	for i := range want {
+		if !times[i].Equal(f[i].Date) || totals[i] != want[i] {
+			t.Errorf("entry %d = %v, %v, want %v, %v", i, times[i], totals[i], f[i].Date, want[i])
+		}
+	}
+
+	times, totals = Forecast{}.CumulativeRain()
+	if len(times) != 0 || len(totals) != 0 {
+		t.Errorf("CumulativeRain() of an empty forecast = %v, %v, want empty", times, totals)
+	}
+}
+
+func TestWithFields(t *testing.T) {
+	const body = `{"list":[{"dt":100,"main":{"temp":20,"humidity":50,"pressure":1010},"wind":{"speed":3}}]}`
+
+	tests := []struct {
+		name    string
+		opts    []Option
+		want    Weather
+		wantErr bool
+	}{
+		{"all fields", nil, Weather{Temperature: 20, Humidity: 50, Pressure: 1010, WindSpeed: 3}, false},
+		{"selected fields", []Option{WithFields("Date", "Temperature")}, Weather{Temperature: 20}, false},
+		{"unknown field", []Option{WithFields("Temperature", "Temprature")}, Weather{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, done := newTestClient(respond(body), tt.opts...)
+			defer done()
+
+			f, err := c.GetForecast(context.Background(), "12345")
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), `"Temprature"`) {
+					t.Errorf("GetForecast() error = %v, want one naming the unknown field", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetForecast() error = %v", err)
+			}
+			got := f[0]
+			if got.Date.Unix() != 100 {
+				t.Errorf("Date = %v, want it populated", got.Date)
+			}
+			if got.Temperature != tt.want.Temperature || got.Humidity != tt.want.Humidity ||
+				got.Pressure != tt.want.Pressure || got.WindSpeed != tt.want.WindSpeed {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithFieldsCurrentWeather(t *testing.T) {
+	const body = `{"dt":100,"main":{"temp":20,"humidity":50,"pressure":1010},"wind":{"speed":3},"coord":{"lat":1,"lon":2}}`
+	c, done := newTestClient(respond(body), WithFields("Date", "Temperature"))
+	defer done()
+
+	got, err := c.GetCurrentWeather(context.Background(), "12345")
+	if err != nil {
+		t.Fatalf("GetCurrentWeather() error = %v", err)
+	}
+	if got.Date.Unix() != 100 || got.Temperature != 20 {
+		t.Errorf("got %+v, want Date and Temperature populated", got)
+	}
+	if got.Humidity != 0 || got.Pressure != 0 || got.WindSpeed != 0 || got.Lat != 0 || got.Lon != 0 {
+		t.Errorf("got %+v, want the other fields zero", got)
+	}
+}
+
+func TestAverageDiurnalRange(t *testing.T) {
+	day := time.Date(2020, 6, 1, 6, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		f    Forecast
+		want float64
+	}{
+		{"empty", nil, 0},
+		{"one day", Forecast{
+			{Date: day, TemperatureMin: 10, TemperatureMax: 14},
+			{Date: day.Add(6 * time.Hour), TemperatureMin: 16, TemperatureMax: 22},
+		}, 12},
+		{"two days", Forecast{
+			{Date: day, TemperatureMin: 10, TemperatureMax: 20},
+			{Date: day.AddDate(0, 0, 1), TemperatureMin: 15, TemperatureMax: 19},
+		}, 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.f.AverageDiurnalRange(); got != tt.want {
+				t.Errorf("AverageDiurnalRange() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClone(t *testing.T) {
+	w := Weather{Date: time.Unix(100, 0), Temperature: 20, Condition: "Clear", Units: Metric}
+	clone := w.Clone()
+	if clone != w {
+		t.Errorf("Clone() = %+v, want %+v", clone, w)
+	}
+	clone.Temperature = 30
+	clone.Condition = "Rain"
+	if w.Temperature != 20 || w.Condition != "Clear" {
+		t.Errorf("changing the clone changed the original to %+v", w)
+	}
+}
+
+func TestWetBulbTemperature(t *testing.T) {
+	tests := []struct {
+		name    string
+		weather Weather
+		want    float64
+	}{
+		// Stull (2011) gives 13.7°C for 20°C at 50% humidity.
+		{"reference", Weather{Temperature: 20, Humidity: 50}, 13.7},
+		{"near saturation", Weather{Temperature: 25, Humidity: 99}, 24.9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.weather.WetBulbTemperature(); math.Abs(got-tt.want) > 0.5 {
+				t.Errorf("WetBulbTemperature() = %.2f, want %v within 0.5", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGeocodeZip(t *testing.T) {
+	tests := []struct {
+		name    string
+		country string
+		wantZip string
+	}{
+		{"default country", "", "10001"},
+		{"explicit country", "US", "10001,US"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var path, zip string
+			c, done := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+				path, zip = r.URL.Path, r.URL.Query().Get("zip")
+				w.Write([]byte(`{"zip":"10001","name":"New York","lat":40.75,"lon":-73.99,"country":"US"}`))
+			})
+			defer done()
+
+			loc, err := c.GeocodeZip(context.Background(), "10001", tt.country)
+			if err != nil {
+				t.Fatalf("GeocodeZip() error = %v", err)
+			}
+			if path != "/geo/1.0/zip" || zip != tt.wantZip {
+				t.Errorf("requested %s with zip %q, want /geo/1.0/zip with %q", path, zip, tt.wantZip)
+			}
+			want := Location{Coords: Coords{Lat: 40.75, Lon: -73.99}, Zip: "10001", Name: "New York", Country: "US"}
+			if loc != want {
+				t.Errorf("GeocodeZip() = %+v, want %+v", loc, want)
+			}
+		})
+	}
+
+	c, done := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	defer done()
+	if _, err := c.GeocodeZip(context.Background(), "00000", ""); !errors.Is(err, ErrCityNotFound) {
+		t.Errorf("GeocodeZip() of an unknown zip: error = %v, want %v", err, ErrCityNotFound)
+	}
+}
+
+func TestSevereWindows(t *testing.T) {
+	at := func(hour, id int, cond string) Weather {
+		return Weather{Date: time.Unix(0, 0).Add(time.Duration(hour) * time.Hour), ConditionID: id, Condition: cond}
+	}
+	hour := func(h int) time.Time { return time.Unix(0, 0).Add(time.Duration(h) * time.Hour) }
+
+	tests := []struct {
+		name string
+		f    Forecast
+		want []SevereWindow
+	}{
+		{"none", Forecast{at(0, 800, "Clear"), at(3, 500, "Rain")}, nil},
+		{"single entry", Forecast{at(0, 800, "Clear"), at(3, 211, "Thunderstorm"), at(6, 800, "Clear")},
+			[]SevereWindow{{Start: hour(3), End: hour(3), Condition: "Thunderstorm"}}},
+		{"worst condition wins", Forecast{at(0, 211, "Thunderstorm"), at(3, 781, "Tornado"), at(6, 771, "Squall")},
+			[]SevereWindow{{Start: hour(0), End: hour(6), Condition: "Tornado"}}},
+		{"separate windows", Forecast{at(0, 771, "Squall"), at(3, 800, "Clear"), at(6, 211, "Thunderstorm")},
+			[]SevereWindow{
+				{Start: hour(0), End: hour(0), Condition: "Squall"},
+				{Start: hour(6), End: hour(6), Condition: "Thunderstorm"},
+			}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.f.SevereWindows()
+			if len(got) != len(tt.want) {
+				t.Fatalf("SevereWindows() = %v, want %v", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("SevereWindows()[%d] = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPeakWindPerDay(t *testing.T) {
+	day := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	at := func(hour int, speed float64) Weather {
+		return Weather{Date: day.Add(time.Duration(hour) * time.Hour), WindSpeed: speed}
+	}
+	f := Forecast{at(3, 4), at(9, 8), at(15, 8), at(21, 2), at(27, 5), at(33, 1)}
+
+	got := f.PeakWindPerDay()
+	want := []Weather{at(9, 8), at(27, 5)}
+	if len(got) != len(want) {
+		t.Fatalf("PeakWindPerDay() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("PeakWindPerDay()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if got := (Forecast{}).PeakWindPerDay(); len(got) != 0 {
+		t.Errorf("PeakWindPerDay() of an empty forecast = %v, want empty", got)
+	}
+}
+
+func TestGetDaySummary(t *testing.T) {
+	const body = `{"lat":33,"lon":35,"date":"2020-03-04",` +
+		`"cloud_cover":{"afternoon":20},"humidity":{"afternoon":40},"precipitation":{"total":1.5},` +
+		`"temperature":{"min":10,"max":20,"morning":12,"afternoon":19,"evening":16,"night":11},` +
+		`"pressure":{"afternoon":1015},"wind":{"max":{"speed":8,"direction":120}}}`
+	date := time.Date(2020, 3, 4, 15, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		lat, lon float64
+		date     time.Time
+		wantErr  bool
+	}{
+		{"valid", 33, 35, date, false},
+		{"bad latitude", 91, 35, date, true},
+		{"bad longitude", 33, -181, date, true},
+		{"no date", 33, 35, time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var query string
+			c, done := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+				q := r.URL.Query()
+				query = r.URL.Path + "?" + q.Get("lat") + "," + q.Get("lon") + "," + q.Get("date")
+				w.Write([]byte(body))
+			}, WithUnits(Metric))
+			defer done()
+
+			got, err := c.GetDaySummary(context.Background(), tt.lat, tt.lon, tt.date)
+			if tt.wantErr {
+				if err == nil || query != "" {
+					t.Errorf("GetDaySummary() error = %v after requesting %q, want an error without a request", err, query)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetDaySummary() error = %v", err)
+			}
+			if want := "/data/3.0/onecall/day_summary?33,35,2020-03-04"; query != want {
+				t.Errorf("requested %q, want %q", query, want)
+			}
+			want := DaySummary{
+				Date:           time.Date(2020, 3, 4, 0, 0, 0, 0, time.UTC),
+				TemperatureMin: 10, TemperatureMax: 20,
+				TemperatureMorning: 12, TemperatureAfternoon: 19, TemperatureEvening: 16, TemperatureNight: 11,
+				Humidity: 40, Pressure: 1015, Clouds: 20, Precipitation: 1.5,
+				WindSpeedMax: 8, WindDirectionMax: 120,
+				Units: Metric, Lat: 33, Lon: 35,
+			}
+			if got != want {
+				t.Errorf("GetDaySummary() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestClearestDay(t *testing.T) {
+	day := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		f      Forecast
+		want   string
+		wantOK bool
+	}{
+		{"empty", nil, "", false},
+		{"clearest", Forecast{
+			{Date: day, Clouds: 60},
+			{Date: day.AddDate(0, 0, 1), Clouds: 10},
+			{Date: day.AddDate(0, 0, 1).Add(3 * time.Hour), Clouds: 30},
+			{Date: day.AddDate(0, 0, 2), Clouds: 25},
+		}, "20200602", true},
+		{"tie goes to earlier day", Forecast{
+			{Date: day, Clouds: 20},
+			{Date: day.AddDate(0, 0, 1), Clouds: 20},
+		}, "20200601", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.f.ClearestDay()
+			if ok != tt.wantOK {
+				t.Fatalf("ClearestDay() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && got.Date.Format("20060102") != tt.want {
+				t.Errorf("ClearestDay() = %v, want %s", got.Date, tt.want)
+			}
+		})
+	}
+}
+
+func TestFeelsColderThan(t *testing.T) {
+	f := Forecast{
+		{Temperature: 10, FeelsLike: 9},
+		{Temperature: 10, FeelsLike: 4},
+		{Temperature: 10, FeelsLike: 5},
+		{Temperature: 10, FeelsLike: 12},
+	}
+
+	tests := []struct {
+		name      string
+		threshold float64
+		want      int
+	}{
+		{"strictly more than threshold", 5, 1},
+		{"small threshold", 0.5, 3},
+		{"negative threshold includes warmer", -3, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := f.FeelsColderThan(tt.threshold); len(got) != tt.want {
+				t.Errorf("FeelsColderThan(%v) = %v, want %d entries", tt.threshold, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackgroundColor(t *testing.T) {
+	noon := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	midnight := noon.Add(12 * time.Hour)
+
+	tests := []struct {
+		name    string
+		weather Weather
+		want    string
+	}{
+		{"clear day", Weather{ConditionID: 800, Date: noon}, "#ffd966"},
+		{"clear night", Weather{ConditionID: 800, Date: midnight}, "#1c2a4a"},
+		{"cloudy night", Weather{ConditionID: 803, Date: midnight}, "#1c2a4a"},
+		{"rain at night", Weather{ConditionID: 501, Date: midnight}, "#6b7f8e"},
+		{"snow", Weather{ConditionID: 601, Date: noon}, "#e8eef2"},
+		{"unknown", Weather{Date: noon}, "#9e9e9e"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.weather.BackgroundColor(); got != tt.want {
+				t.Errorf("BackgroundColor() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeToReach(t *testing.T) {
+	hour := func(h float64) time.Time { return time.Unix(0, 0).Add(time.Duration(h * float64(time.Hour))) }
+	var f Forecast
+	for i, temp := range []float64{10, 16, 22, 14} {
+		f = append(f, Weather{Date: hour(float64(i) * 3), Temperature: temp})
+	}
+
+	tests := []struct {
+		name   string
+		target float64
+		rising bool
+		want   time.Time
+		wantOK bool
+	}{
+		{"rising", 13, true, hour(1.5), true},
+		{"rising to an entry", 16, true, hour(3), true},
+		{"falling", 18, false, hour(7.5), true},
+		{"never that warm", 30, true, time.Time{}, false},
+		{"never falls that far", 10, false, time.Time{}, false},
+		{"already above", 5, true, time.Time{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := f.TimeToReach(tt.target, tt.rising)
+			if ok != tt.wantOK || !got.Equal(tt.want) {
+				t.Errorf("TimeToReach(%v, %v) = %v, %v, want %v, %v", tt.target, tt.rising, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestToOpenWeatherJSONRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		w    Weather
+	}{
+		{"full", Weather{
+			Date:                time.Unix(1600000000, 0),
+			Temperature:         21.5,
+			TemperatureMin:      18,
+			TemperatureMax:      24,
+			FeelsLike:           20,
+			Humidity:            55,
+			Pressure:            1012,
+			SeaLevelPressure:    1015,
+			GroundLevelPressure: 990,
+			ConditionID:         501,
+			Condition:           "Rain",
+			Clouds:              75,
+			Rain:                1.2,
+			Sunrise:             time.Unix(1599980000, 0),
+			Sunset:              time.Unix(1600025000, 0),
+			WindSpeed:           4.5,
+			WindDirection:       200,
+			Units:               Metric,
+			Lat:                 51.5,
+			Lon:                 -0.12,
+		}},
+		{"no sun times", Weather{
+			Date:                time.Unix(1600000000, 0),
+			Temperature:         5,
+			Pressure:            1000,
+			SeaLevelPressure:    1000,
+			GroundLevelPressure: 1000,
+			ConditionID:         800,
+			Condition:           "Clear",
+			Units:               Metric,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := tt.w.ToOpenWeatherJSON()
+			if err != nil {
+				t.Fatalf("ToOpenWeatherJSON() error = %v", err)
+			}
+			c, done := newTestClient(respond(string(b)), WithUnits(Metric))
+			defer done()
+
+			got, err := c.GetCurrentWeather(context.Background(), "12345")
+			if err != nil {
+				t.Fatalf("GetCurrentWeather() error = %v", err)
+			}
+			if got != tt.w {
+				t.Errorf("decoded %+v,\nwant %+v", got, tt.w)
+			}
+		})
+	}
+}
+
+func TestWithClock(t *testing.T) {
+	const body = `{"list":[{"dt":0,"main":{"temp":1}},{"dt":43200,"main":{"temp":2}},{"dt":86400,"main":{"temp":3}}]}`
+
+	tests := []struct {
+		name      string
+		now       time.Time
+		wantNext  float64
+		wantOK    bool
+		wantTomor bool
+	}{
+		{"start", time.Unix(0, 0), 1, true, true},
+		{"between entries", time.Unix(3600, 0), 2, true, true},
+		{"past the end", time.Unix(90000, 0), 0, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := tt.now
+			c, done := newTestClient(respond(body), WithClock(func() time.Time { return now }))
+			defer done()
+
+			if got := c.Now(); !got.Equal(now) {
+				t.Errorf("Now() = %v, want %v", got, now)
+			}
+			next, ok, err := c.NextForecast(context.Background(), "12345")
+			if err != nil {
+				t.Fatalf("NextForecast() error = %v", err)
+			}
+			if ok != tt.wantOK || next.Temperature != tt.wantNext {
+				t.Errorf("NextForecast() = %v, %v, want temperature %v, %v", next.Temperature, ok, tt.wantNext, tt.wantOK)
+			}
+			_, ok, err = c.GetTomorrow(context.Background(), "12345")
+			if err != nil {
+				t.Fatalf("GetTomorrow() error = %v", err)
+			}
+			if ok != tt.wantTomor {
+				t.Errorf("GetTomorrow() ok = %v, want %v", ok, tt.wantTomor)
+			}
+		})
+	}
+
+	before := time.Now()
+	if got := NewClient().Now(); got.Before(before) || got.After(time.Now()) {
+		t.Errorf("Now() without WithClock = %v, want the current time", got)
+	}
+}